@@ -47,6 +47,30 @@
   [[ "$output" =~ "Containers must not run as root" ]]
 }
 
+@test "Can select the namespaces and parser of each file with rules from the configuration file" {
+  cd examples/rules
+  run ../../conftest test k8s Dockerfile
+  [ "$status" -eq 1 ]
+  [[ "$output" =~ "FAIL - k8s/service.yaml - kubernetes - Service hello-kubernetes must not be of type LoadBalancer" ]]
+  [[ "$output" =~ "FAIL - Dockerfile - docker - Image openjdk:8-jdk-alpine must not be used" ]]
+}
+
+@test "Can select the files of a namespace with __conftest__ in the policies" {
+  cd examples/selector
+  run ../../conftest test --all-namespaces k8s Dockerfile
+  [ "$status" -eq 1 ]
+  [[ "$output" =~ "FAIL - Dockerfile - docker - Image openjdk:8-jdk-alpine must not be used" ]]
+  [[ "$output" =~ "3 tests, 0 passed, 1 warning, 2 failures, 0 exceptions" ]]
+}
+
+@test "Can use the metadata of the file in the policies" {
+  cd examples/file-metadata
+  run ../../conftest test prod dev
+  [ "$status" -eq 1 ]
+  [[ "$output" =~ "FAIL - prod/deployment.yaml - main - Deployment hello-kubernetes in prod/deployment.yaml (document 0) must have at least 3 replicas" ]]
+  [[ "$output" =~ "3 tests, 2 passed, 0 warnings, 1 failure, 0 exceptions" ]]
+}
+
 @test "Has version flag" {
   run ./conftest --version
   [ "$status" -eq 0 ]
@@ -168,6 +192,13 @@
   [[ "$output" =~ "ALB \`my-alb-listener\` is using HTTP rather than HTTP" ]]
 }
 
+@test "Can parse a Terraform module directory" {
+  run ./conftest test --parser terraform --follow-modules -p examples/terraform/policy examples/terraform
+  [ "$status" -eq 1 ]
+  [[ "$output" =~ "Instance \`web\` uses instance type \`m5.24xlarge\` which is not allowed" ]]
+  [[ "$output" =~ "Instance \`worker\` of module \`worker\` uses instance type \`c5.large\` which is not allowed" ]]
+}
+
 @test "Can parse stdin with parser flag" {
   run bash -c "cat examples/ini/grafana.ini | ./conftest test -p examples/ini/policy --parser ini -"
   [ "$status" -eq 1 ]
@@ -175,6 +206,19 @@
   [[ "$output" != *"Basic auth should be enabled"* ]]
 }
 
+@test "Can detect the format of stdin with --detect-format" {
+  run bash -c "cat examples/ini/grafana.ini | ./conftest test -p examples/ini/policy --detect-format -"
+  [ "$status" -eq 1 ]
+  [[ "$output" =~ "Users should verify their e-mail address" ]]
+}
+
+@test "Can continue testing when a file fails to parse with --continue-on-error" {
+  run bash -c "echo 'kind: [' | ./conftest test --continue-on-error -p examples/kubernetes/policy examples/kubernetes/service.yaml -"
+  [ "$status" -eq 3 ]
+  [[ "$output" =~ "ERR - - parser unmarshal" ]]
+  [[ "$output" =~ "Found service hello-kubernetes but services are not allowed" ]]
+}
+
 @test "Using --parser should force the chosen parser and fail the rego policy" {
   run ./conftest test -p examples/terraform/policy/gke.rego examples/terraform/gke.tf --parser ini
   [ "$status" -eq 1 ]
@@ -215,6 +259,12 @@
   [[ "$output" =~ "\"Cmd\": \"from\"" ]]
 }
 
+@test "Can report the detected parser with 'conftest parse'" {
+  run bash -c "cat examples/ini/grafana.ini | ./conftest parse --detect-format -"
+  [ "$status" -eq 0 ]
+  [[ "$output" =~ "-: using parser ini" ]]
+}
+
 @test "Can output tap format in test command" {
   run ./conftest test -p examples/kubernetes/policy/ -o tap examples/kubernetes/deployment.yaml
   [[ "$output" =~ "not ok" ]]
@@ -251,6 +301,131 @@
   [[ "$output" =~ "Cannot expose port" ]]
 }
 
+@test "Can root data at a prefix" {
+  run ./conftest test -p examples/data/policy -d examples/data/exclusions:vendor examples/data/service.yaml
+  [ "$status" -eq 0 ]
+
+  run ./conftest test -p examples/data/policy --data-prefix -d examples/data/exclusions examples/data/service.yaml
+  [ "$status" -eq 0 ]
+}
+
+@test "Can pass parameters to policies" {
+  cd examples/params
+  run ../../conftest test --param-file prod.yaml deployment.yaml
+  [ "$status" -eq 0 ]
+
+  run ../../conftest test --param-file prod.yaml --param max_replicas=2 --param env=staging deployment.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" =~ "hello-kubernetes must not have more than 2 replicas in staging" ]]
+}
+
+@test "Does not expose environment variables to policies by default" {
+  cd examples/runtime-env
+  CONFTEST_ENVIRONMENT=production run ../../conftest test deployment.yaml
+  [ "$status" -eq 0 ]
+  [[ "$output" =~ "reads the environment variable CONFTEST_ENVIRONMENT" ]]
+}
+
+@test "Can expose environment variables to policies with --runtime-env" {
+  cd examples/runtime-env
+  CONFTEST_ENVIRONMENT=production run ../../conftest test --runtime-env 'CONFTEST_*' deployment.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" =~ "hello-kubernetes must be labelled with the production environment" ]]
+
+  CONFTEST_ENVIRONMENT=production run ../../conftest verify --runtime-env 'CONFTEST_*'
+  [ "$status" -eq 0 ]
+}
+
+@test "Can forbid nondeterministic builtins with --strict-builtins" {
+  cd examples/capabilities
+  run ../../conftest test certificate.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" =~ "Certificate example-com has expired" ]]
+
+  run ../../conftest test --strict-builtins certificate.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" =~ "undefined function time.now_ns" ]]
+}
+
+@test "Can restrict builtins with a capabilities file" {
+  cd examples/capabilities
+  run ../../conftest test --capabilities capabilities.json certificate.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" =~ "undefined function time.now_ns" ]]
+}
+
+@test "Can parse embedded configurations with conftest.parse_config" {
+  cd examples/parse-config
+  run ../../conftest test configmap.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" =~ "application must not enable debug logging" ]]
+  [[ "$output" =~ "application must connect to the database over SSL" ]]
+
+  run ../../conftest verify
+  [ "$status" -eq 0 ]
+}
+
+@test "Can read files relative to the input with conftest.file" {
+  cd examples/sibling-files
+  run ../../conftest test .
+  [ "$status" -eq 1 ]
+  [[ "$output" =~ "Deployment hello-kubernetes of base/kustomization.yaml must run as non-root" ]]
+  [[ "$output" =~ "app/Dockerfile must not copy the .git directory" ]]
+}
+
+@test "Reports rules that exceed --query-timeout as errors" {
+  cd examples/timeout
+  run ../../conftest test --query-timeout 1s deployment.yaml
+  [ "$status" -eq 3 ]
+  [[ "$output" =~ "evaluating data.main.deny against deployment.yaml timed out after 1s" ]]
+  [[ "$output" =~ "hello-kubernetes should run as non-root" ]]
+}
+
+@test "Stops the test run after --timeout" {
+  cd examples/timeout
+  run ../../conftest test --timeout 1s deployment.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" =~ "test run timed out after 1s" ]]
+}
+
+@test "Can profile the rules of the policies with --profile" {
+  cd examples/timeout
+  run ../../conftest test --profile --query-timeout 1s deployment.yaml
+  [ "$status" -eq 3 ]
+  [[ "$output" =~ "Slowest rules" ]]
+  [[ "$output" =~ "policy/ports.rego:5" ]]
+
+  run ../../conftest test --profile --profile-format json --query-timeout 1s deployment.yaml
+  [ "$status" -eq 3 ]
+  [[ "$output" =~ "\"location\": \"policy/ports.rego:8\"" ]]
+}
+
+@test "Can report the coverage of the policies with --coverage" {
+  run ./conftest verify -p examples/kubernetes/policy --coverage
+  [ "$status" -eq 0 ]
+  [[ "$output" =~ "\"coverage\": 86.11" ]]
+
+  run ./conftest verify -p examples/kubernetes/policy --coverage --coverage-format lcov --coverage-file coverage.lcov
+  [ "$status" -eq 0 ]
+  run grep -c "SF:examples/kubernetes/policy" coverage.lcov
+  rm -f coverage.lcov
+  [ "$output" -eq 5 ]
+}
+
+@test "Fails when the coverage is below --coverage-threshold" {
+  run ./conftest verify -p examples/kubernetes/policy --coverage-threshold 90
+  [ "$status" -eq 1 ]
+  [[ "$output" =~ "4 tests, 4 passed" ]]
+  [[ "$output" =~ "coverage of 86.11% is below the threshold of 90.00%" ]]
+
+  run ./conftest test --no-fail -p examples/kubernetes/policy --coverage-threshold 90 examples/kubernetes/deployment.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" =~ "coverage of 72.22% is below the threshold of 90.00%" ]]
+
+  run ./conftest verify -p examples/kubernetes/policy --coverage-threshold 80
+  [ "$status" -eq 0 ]
+}
+
 @test "Can load data in unit tests" {
   run ./conftest verify -p examples/data/policy -d examples/data/exclusions examples/data/service.yaml
   [ "$status" -eq 0 ]
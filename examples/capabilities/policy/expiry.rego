@@ -0,0 +1,9 @@
+package main
+
+# The result of this policy depends on when it is evaluated, so it
+# fails to compile when the builtins are restricted with --strict-builtins.
+deny[msg] {
+	input.kind == "Certificate"
+	time.parse_rfc3339_ns(input.status.notAfter) < time.now_ns()
+	msg := sprintf("Certificate %s has expired", [input.metadata.name])
+}
@@ -0,0 +1,9 @@
+package main
+
+deny[msg] {
+  startswith(data.conftest.file.path, "prod/")
+  input.kind == "Deployment"
+  input.spec.replicas < 3
+
+  msg := sprintf("Deployment %s in %s (document %d) must have at least 3 replicas", [input.metadata.name, data.conftest.file.path, data.conftest.file.document_index])
+}
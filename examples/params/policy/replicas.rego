@@ -0,0 +1,15 @@
+package main
+
+params := data.conftest.params
+
+deny[msg] {
+	input.kind == "Deployment"
+	input.spec.replicas > params.max_replicas
+	msg := sprintf("%s must not have more than %v replicas in %s", [input.metadata.name, params.max_replicas, params.env])
+}
+
+deny[msg] {
+	input.kind == "Deployment"
+	input.spec.replicas < params.min_replicas
+	msg := sprintf("%s must have at least %v replicas in %s", [input.metadata.name, params.min_replicas, params.env])
+}
@@ -0,0 +1,17 @@
+package main
+
+application := conftest.parse_config("yaml", input.data["application.yaml"])
+
+settings := conftest.parse_config("ini", input.data["settings.ini"])
+
+deny[msg] {
+	input.kind == "ConfigMap"
+	application.logging.level == "debug"
+	msg := sprintf("%s must not enable debug logging", [input.metadata.name])
+}
+
+deny[msg] {
+	input.kind == "ConfigMap"
+	settings.database.ssl == false
+	msg := sprintf("%s must connect to the database over SSL", [input.metadata.name])
+}
@@ -0,0 +1,13 @@
+package main
+
+test_debug_logging {
+	deny["application must not enable debug logging"] with input as {
+		"kind": "ConfigMap",
+		"metadata": {"name": "application"},
+		"data": {"application.yaml": "logging:\n  level: debug\n", "settings.ini": ""},
+	}
+}
+
+test_invalid_config {
+	not conftest.parse_config("json", "{")
+}
@@ -0,0 +1,8 @@
+package docker
+
+deny[msg] {
+  input[i].Cmd == "from"
+  image := input[i].Value[0]
+  startswith(image, "openjdk:8")
+  msg := sprintf("Image %s must not be used", [image])
+}
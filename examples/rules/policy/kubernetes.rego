@@ -0,0 +1,7 @@
+package kubernetes
+
+deny[msg] {
+  input.kind == "Service"
+  input.spec.type == "LoadBalancer"
+  msg := sprintf("Service %s must not be of type LoadBalancer", [input.metadata.name])
+}
@@ -0,0 +1,11 @@
+package main
+
+runtime := opa.runtime()
+
+# The environment is read from the CONFTEST_ENVIRONMENT variable, which
+# is only exposed when allowed with --runtime-env.
+deny[msg] {
+	environment := runtime.env.CONFTEST_ENVIRONMENT
+	input.metadata.labels.environment != environment
+	msg := sprintf("%s must be labelled with the %s environment", [input.metadata.name, environment])
+}
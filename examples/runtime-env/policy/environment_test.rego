@@ -0,0 +1,6 @@
+package main
+
+test_environment_label {
+	deny["hello-kubernetes must be labelled with the production environment"] with input as {"metadata": {"name": "hello-kubernetes", "labels": {"environment": "staging"}}}
+		with runtime as {"env": {"CONFTEST_ENVIRONMENT": "production"}}
+}
@@ -0,0 +1,11 @@
+package docker
+
+# The policies in this package only apply to Dockerfiles.
+__conftest__ := {"parsers": ["dockerfile"]}
+
+deny[msg] {
+  input[i].Cmd == "from"
+  image := input[i].Value[0]
+  startswith(image, "openjdk:8")
+  msg := sprintf("Image %s must not be used", [image])
+}
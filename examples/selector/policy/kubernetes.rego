@@ -0,0 +1,15 @@
+package kubernetes
+
+# The policies in this package only apply to the manifests in the k8s directory.
+__conftest__ := {"files": ["k8s/**/*.yaml"], "parsers": ["yaml"]}
+
+deny[msg] {
+  input.kind == "Service"
+  input.spec.type == "LoadBalancer"
+  msg := sprintf("Service %s must not be of type LoadBalancer", [input.metadata.name])
+}
+
+warn[msg] {
+  not input.metadata.labels.team
+  msg := sprintf("%s %s should have a team label", [input.kind, input.metadata.name])
+}
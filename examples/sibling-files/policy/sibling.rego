@@ -0,0 +1,23 @@
+package main
+
+# The resources of a Kustomization are read relative to the kustomization.yaml.
+deny[msg] {
+	input.kind == "Kustomization"
+	resource := conftest.file(input.resources[_])
+	resource.kind == "Deployment"
+	not resource.spec.template.spec.securityContext.runAsNonRoot
+	msg := sprintf("Deployment %s of %s must run as non-root", [resource.metadata.name, data.conftest.file.path])
+}
+
+# The .dockerignore next to a Dockerfile is parsed with the ignore parser.
+deny[msg] {
+	data.conftest.file.parser == "dockerfile"
+	not git_ignored
+	msg := sprintf("%s must not copy the .git directory, add it to .dockerignore", [data.conftest.file.path])
+}
+
+git_ignored {
+	entry := conftest.file(".dockerignore")[_][_]
+	entry.Kind == "Path"
+	entry.Value == ".git"
+}
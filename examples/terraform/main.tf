@@ -0,0 +1,17 @@
+locals {
+  name = "${var.environment}-web"
+}
+
+resource "aws_instance" "web" {
+  ami           = "ami-0c55b159cbfe678c0"
+  instance_type = var.instance_type
+
+  tags = {
+    Name = local.name
+  }
+}
+
+module "worker" {
+  source        = "./modules/instance"
+  instance_type = var.worker_instance_type
+}
@@ -0,0 +1,6 @@
+variable "instance_type" {}
+
+resource "aws_instance" "worker" {
+  ami           = "ami-0c55b159cbfe678c0"
+  instance_type = var.instance_type
+}
@@ -0,0 +1,19 @@
+package main
+
+allowed_instance_types = ["t2.micro", "t2.small", "t2.medium"]
+
+deny[msg] {
+	instance := input.resource.aws_instance[name]
+	not allowed_instance_type(instance.instance_type)
+	msg = sprintf("Instance `%v` uses instance type `%v` which is not allowed", [name, instance.instance_type])
+}
+
+deny[msg] {
+	instance := input.module[module].__module.resource.aws_instance[name]
+	not allowed_instance_type(instance.instance_type)
+	msg = sprintf("Instance `%v` of module `%v` uses instance type `%v` which is not allowed", [name, module, instance.instance_type])
+}
+
+allowed_instance_type(instance_type) {
+	instance_type == allowed_instance_types[_]
+}
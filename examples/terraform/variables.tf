@@ -0,0 +1,11 @@
+variable "environment" {
+  default = "dev"
+}
+
+variable "instance_type" {
+  default = "t2.micro"
+}
+
+variable "worker_instance_type" {
+  default = "t2.micro"
+}
@@ -0,0 +1,16 @@
+package main
+
+# This rule compares every pair of numbers in a large range, so its evaluation
+# takes far too long. Use --query-timeout to report it as an error instead.
+deny[msg] {
+	a := numbers.range(1, 100000)[_]
+	b := numbers.range(1, 100000)[_]
+	a + b < 0
+	msg := "this message is never reported"
+}
+
+warn[msg] {
+	input.kind == "Deployment"
+	not input.spec.template.spec.securityContext.runAsNonRoot
+	msg := sprintf("%s should run as non-root", [input.metadata.name])
+}
@@ -14,6 +14,7 @@ require (
 	github.com/google/go-jsonnet v0.17.0
 	github.com/hashicorp/go-getter v1.5.3
 	github.com/hashicorp/hcl v1.0.0
+	github.com/hashicorp/hcl/v2 v2.6.0
 	github.com/jstemmer/go-junit-report v0.9.1
 	github.com/logrusorgru/aurora v2.0.3+incompatible
 	github.com/moby/buildkit v0.8.2
@@ -24,6 +25,7 @@ require (
 	github.com/spf13/cobra v1.1.3
 	github.com/spf13/viper v1.7.1
 	github.com/tmccombs/hcl2json v0.3.1
+	github.com/zclconf/go-cty v1.6.1
 	golang.org/x/lint v0.0.0-20201208152925-83fdc39ff7b5 // indirect
 	olympos.io/encoding/edn v0.0.0-20201019073823-d3554ca0b0a3
 	rsc.io/letsencrypt v0.0.3 // indirect
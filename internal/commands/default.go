@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/open-policy-agent/conftest/parser"
 	"github.com/open-policy-agent/conftest/plugin"
 
 	"github.com/spf13/cobra"
@@ -35,7 +38,7 @@ func NewDefaultCommand() *cobra.Command {
 	viper.AutomaticEnv()
 
 	logger := log.New(os.Stdout, "", log.LstdFlags)
-	ctx := context.Background()
+	ctx := interruptContext()
 
 	if err := viper.ReadInConfig(); err != nil {
 		var e viper.ConfigFileNotFoundError
@@ -44,6 +47,15 @@ func NewDefaultCommand() *cobra.Command {
 		}
 	}
 
+	plugins, err := plugin.FindAll()
+	if err != nil {
+		logger.Fatalf("error finding plugins: %v", err)
+	}
+
+	// Parsers provided by plugins are registered before the commands are
+	// created so that they are included in the list of valid parsers.
+	registerPluginParsers(ctx, plugins)
+
 	cmd.AddCommand(NewTestCommand(ctx))
 	cmd.AddCommand(NewParseCommand(ctx))
 	cmd.AddCommand(NewPushCommand(ctx, logger))
@@ -52,21 +64,42 @@ func NewDefaultCommand() *cobra.Command {
 	cmd.AddCommand(NewPluginCommand(ctx))
 	cmd.AddCommand(NewFormatCommand(ctx))
 
-	pluginCmds, err := loadPlugins(ctx)
-	if err != nil {
-		logger.Fatalf("error loading plugins: %v", err)
-	}
-
-	cmd.AddCommand(pluginCmds...)
+	cmd.AddCommand(loadPlugins(ctx, plugins)...)
 	return &cmd
 }
 
-func loadPlugins(ctx context.Context) ([]*cobra.Command, error) {
-	plugins, err := plugin.FindAll()
-	if err != nil {
-		return nil, fmt.Errorf("find plugins: %v", err)
+// interruptContext returns a context that is cancelled when the process is
+// interrupted, so that commands stop evaluating policies and return an error.
+// A second interrupt terminates the process immediately.
+func interruptContext() context.Context {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	return ctx
+}
+
+// registerPluginParsers registers the parsers provided by the plugins. A
+// parser that conflicts with an already registered parser is skipped with a
+// warning, so that a broken plugin does not prevent any command from running.
+func registerPluginParsers(ctx context.Context, plugins []*plugin.Plugin) {
+	for _, p := range plugins {
+		for i := range p.Parsers {
+			pluginParser := p.Parsers[i].WithContext(ctx)
+			factory := func() parser.Parser {
+				return pluginParser
+			}
+
+			if err := parser.TryRegister(pluginParser.Name, factory, pluginParser.Extensions...); err != nil {
+				fmt.Fprintf(os.Stderr, "WARN: skipping parser of plugin %s: %v\n", p.Name, err)
+			}
+		}
 	}
+}
 
+func loadPlugins(ctx context.Context, plugins []*plugin.Plugin) []*cobra.Command {
 	var cmds []*cobra.Command
 	for _, plugin := range plugins {
 		cmd := cobra.Command{
@@ -86,5 +119,5 @@ func loadPlugins(ctx context.Context) ([]*cobra.Command, error) {
 		cmds = append(cmds, &cmd)
 	}
 
-	return cmds, nil
+	return cmds
 }
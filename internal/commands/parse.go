@@ -3,8 +3,10 @@ package commands
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/open-policy-agent/conftest/parser"
+	"github.com/open-policy-agent/conftest/parser/docker"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -19,6 +21,11 @@ the '--parser' flag can be used to explicitly set the parser, e.g.:
 
 	$ conftest parse --parser toml <input-file(s)>
 
+Alternatively, the '--detect-format' flag detects the format from the contents of the
+input and reports the chosen parser, e.g.:
+
+	$ kubectl get service my-service -o json | conftest parse --detect-format -
+
 See the documentation of the '--parser' flag for the supported parsers.
 `
 
@@ -30,7 +37,7 @@ func NewParseCommand(ctx context.Context) *cobra.Command {
 		Short: "Print out structured data from your input files",
 		Long:  parseDesc,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			flagNames := []string{"parser", "combine"}
+			flagNames := []string{"build-arg", "combine", "detect-format", "expand-args", "follow-modules", "hcl2-ranges", "parser"}
 			for _, name := range flagNames {
 				if err := viper.BindPFlag(name, cmd.Flags().Lookup(name)); err != nil {
 					return fmt.Errorf("bind flag: %w", err)
@@ -40,17 +47,28 @@ func NewParseCommand(ctx context.Context) *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, files []string) error {
-			var configurations map[string]interface{}
-			var err error
-			if viper.GetString("parser") != "" {
-				configurations, err = parser.ParseConfigurationsAs(files, viper.GetString("parser"))
-			} else {
-				configurations, err = parser.ParseConfigurations(files)
+			options := parser.Options{
+				Parser:        viper.GetString("parser"),
+				ExpandArgs:    viper.GetBool("expand-args"),
+				BuildArgs:     docker.ParseBuildArgs(viper.GetStringSlice("build-arg")),
+				FollowModules: viper.GetBool("follow-modules"),
+				Ranges:        viper.GetBool("hcl2-ranges"),
+				DetectFormat:  viper.GetBool("detect-format"),
 			}
+
+			configurations, parsers, err := parser.ParseConfigurationsWithParsers(files, options)
 			if err != nil {
 				return fmt.Errorf("parse configurations: %w", err)
 			}
 
+			// When detecting formats, the chosen parsers are reported on
+			// standard error so that the output remains valid JSON.
+			if options.DetectFormat {
+				for _, file := range files {
+					fmt.Fprintf(os.Stderr, "%s: using parser %s\n", file, parsers[file])
+				}
+			}
+
 			var output string
 			if viper.GetBool("combine") {
 				output, err = parser.FormatCombined(configurations)
@@ -67,6 +85,11 @@ func NewParseCommand(ctx context.Context) *cobra.Command {
 	}
 
 	cmd.Flags().BoolP("combine", "", false, "Combine all config files to be evaluated together")
+	cmd.Flags().Bool("detect-format", false, "Detect the format of files with an unknown or missing extension, and of stdin, from their contents")
+	cmd.Flags().Bool("expand-args", false, "Expand ARG and ENV references in Dockerfiles")
+	cmd.Flags().Bool("follow-modules", false, "Load Terraform modules with a local source when using the terraform parser")
+	cmd.Flags().Bool("hcl2-ranges", false, "Add the source range of each HCL2 block under the __range key")
+	cmd.Flags().StringSlice("build-arg", []string{}, "Build arguments (KEY=VALUE) to use when expanding Dockerfiles")
 	cmd.Flags().String("parser", "", fmt.Sprintf("Parser to use to parse the configurations. Valid parsers: %s", parser.Parsers()))
 
 	return &cmd
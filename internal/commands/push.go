@@ -13,6 +13,7 @@ import (
 	orascontext "github.com/deislabs/oras/pkg/context"
 	"github.com/deislabs/oras/pkg/oras"
 	"github.com/open-policy-agent/conftest/policy"
+	"github.com/open-policy-agent/opa/ast"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -59,8 +60,11 @@ func NewPushCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
 		Long:  pushDesc,
 		Args:  cobra.ExactArgs(1),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			if err := viper.BindPFlag("policy", cmd.Flags().Lookup("policy")); err != nil {
-				return fmt.Errorf("bind flag: %w", err)
+			flagNames := []string{"capabilities", "policy", "strict-builtins"}
+			for _, name := range flagNames {
+				if err := viper.BindPFlag(name, cmd.Flags().Lookup(name)); err != nil {
+					return fmt.Errorf("bind flag: %w", err)
+				}
 			}
 
 			return nil
@@ -88,8 +92,13 @@ func NewPushCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
 				repository = repository + ":latest"
 			}
 
+			capabilities, err := policy.LoadCapabilities(viper.GetString("capabilities"), viper.GetBool("strict-builtins"))
+			if err != nil {
+				return fmt.Errorf("load capabilities: %w", err)
+			}
+
 			logger.Printf("pushing bundle to: %s", repository)
-			manifest, err := pushBundle(ctx, repository, viper.GetString("policy"))
+			manifest, err := pushBundle(ctx, repository, viper.GetString("policy"), capabilities)
 			if err != nil {
 				return fmt.Errorf("push bundle: %w", err)
 			}
@@ -99,12 +108,14 @@ func NewPushCommand(ctx context.Context, logger *log.Logger) *cobra.Command {
 		},
 	}
 
+	cmd.Flags().String("capabilities", "", "Path to an OPA capabilities file that lists the builtins the policies may use")
+	cmd.Flags().Bool("strict-builtins", false, "Forbid builtins that access the network or are nondeterministic, such as http.send and time.now_ns")
 	cmd.Flags().StringP("policy", "p", "policy", "Directory to push as a bundle")
 
 	return &cmd
 }
 
-func pushBundle(ctx context.Context, repository string, path string) (*ocispec.Descriptor, error) {
+func pushBundle(ctx context.Context, repository string, path string, capabilities *ast.Capabilities) (*ocispec.Descriptor, error) {
 	cli, err := auth.NewClient()
 	if err != nil {
 		return nil, fmt.Errorf("get auth client: %w", err)
@@ -116,7 +127,7 @@ func pushBundle(ctx context.Context, repository string, path string) (*ocispec.D
 	}
 
 	memoryStore := content.NewMemoryStore()
-	layers, err := buildLayers(ctx, memoryStore, path)
+	layers, err := buildLayers(ctx, memoryStore, path, capabilities)
 	if err != nil {
 		return nil, fmt.Errorf("building layers: %w", err)
 	}
@@ -130,8 +141,8 @@ func pushBundle(ctx context.Context, repository string, path string) (*ocispec.D
 	return &manifest, nil
 }
 
-func buildLayers(ctx context.Context, memoryStore *content.Memorystore, path string) ([]ocispec.Descriptor, error) {
-	engine, err := policy.LoadWithData(ctx, []string{path}, []string{path})
+func buildLayers(ctx context.Context, memoryStore *content.Memorystore, path string, capabilities *ast.Capabilities) ([]ocispec.Descriptor, error) {
+	engine, err := policy.New(ctx, policy.WithPolicies(path), policy.WithData(path), policy.WithCapabilities(capabilities))
 	if err != nil {
 		return nil, fmt.Errorf("load: %w", err)
 	}
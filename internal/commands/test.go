@@ -2,12 +2,12 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
 
-	"github.com/open-policy-agent/conftest/internal/runner"
 	"github.com/open-policy-agent/conftest/output"
 	"github.com/open-policy-agent/conftest/parser"
+	"github.com/open-policy-agent/conftest/runner"
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/storage"
 	"github.com/spf13/cobra"
@@ -33,14 +33,24 @@ The location can be overridden with the '--policy' flag, e.g.:
 Some policies are dependant on external data. This data is loaded in seperatly 
 from policies. The location of any data directory or file can be specified with 
 the '--data' flag. If a directory is specified, it will be recursively searched for 
-any data files. Right now any '.json' or '.yaml' file will be loaded in 
+any data files. Any file in a format supported by conftest will be loaded in 
 and made available in the Rego policies. Data will be made available in Rego based on 
-the file path where the data was found. For example, if data is stored 
+the structure of the data that was loaded, merged at the root of the data document. 
+With the '--data-nested' flag, data is instead placed under the directory where it 
+was found, relative to the data path. For example, if data is stored 
 under 'policy/exceptions/my_data.yaml', and we execute the following command:
 
-	$ conftest test --data policy <input-file>
+	$ conftest test --data-nested --data policy <input-file>
 
-The data is available under 'import data.exceptions'.
+The data is available under 'import data.exceptions'. The data of a path can also be 
+rooted at a prefix, either with the 'path:prefix' syntax or by using the name of its 
+directory with the '--data-prefix' flag:
+
+	$ conftest test --data policy/exceptions:company.exceptions <input-file>
+	$ conftest test --data-prefix --data policy/exceptions <input-file>
+
+The data is then available under 'import data.company.exceptions' and 
+'import data.exceptions' respectively.
 
 The test command supports the '--output' flag to specify the type, e.g.:
 
@@ -64,6 +74,27 @@ It expects one or more urls to fetch the latest policies from, e.g.:
 
 See the pull command for more details on supported protocols for fetching policies.
 
+The '--timeout' flag limits the duration of the whole test run, while the
+'--query-timeout' flag limits the duration of the evaluation of each rule
+against each file. A rule that takes too long is reported as an error of the
+file, and the remaining rules are still evaluated, e.g.
+
+	$ conftest test --timeout 5m --query-timeout 10s <input-file>
+
+The '--profile' flag reports the slowest expressions and rules of the policies,
+aggregated across all of the files, together with the time spent evaluating each
+rule against each file. The profile is written to stderr as tables, or as JSON
+with the '--profile-format' flag, e.g.
+
+	$ conftest test --profile --profile-format json <input-file>
+
+The '--coverage' flag reports which lines of the policies were evaluated, per
+policy and overall. The report is written to stderr, or to the file given with
+the '--coverage-file' flag, as JSON, Cobertura or lcov. With the
+'--coverage-threshold' flag, the run fails when the coverage is too low, e.g.
+
+	$ conftest test --coverage --coverage-format lcov --coverage-file policy.lcov <input-file>
+
 When debugging policies it can be useful to use a more verbose policy evaluation output. By using the '--trace' flag
 the output will include a detailed trace of how the policy was evaluated, e.g.
 
@@ -84,7 +115,7 @@ func NewTestCommand(ctx context.Context) *cobra.Command {
 		Long:  testDesc,
 		Args:  cobra.MinimumNArgs(1),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			flagNames := []string{"all-namespaces", "combine", "data", "fail-on-warn", "ignore", "namespace", "no-color", "no-fail", "suppress-exceptions", "output", "parser", "policy", "trace", "update"}
+			flagNames := []string{"all-namespaces", "build-arg", "capabilities", "combine", "continue-on-error", "coverage", "coverage-file", "coverage-format", "coverage-threshold", "data", "data-nested", "data-prefix", "detect-format", "expand-args", "fail-on-warn", "follow-modules", "gitignore", "hcl2-ranges", "ignore", "include", "namespace", "no-color", "no-fail", "suppress-exceptions", "output", "param", "param-file", "parser", "policy", "profile", "profile-format", "profile-limit", "query-timeout", "runtime-env", "strict-builtins", "timeout", "trace", "update"}
 			for _, name := range flagNames {
 				if err := viper.BindPFlag(name, cmd.Flags().Lookup(name)); err != nil {
 					return fmt.Errorf("bind flag: %w", err)
@@ -95,35 +126,35 @@ func NewTestCommand(ctx context.Context) *cobra.Command {
 		},
 
 		RunE: func(cmd *cobra.Command, fileList []string) error {
-			var runner runner.TestRunner
-			if err := viper.Unmarshal(&runner); err != nil {
+			var testRunner runner.TestRunner
+			if err := viper.Unmarshal(&testRunner); err != nil {
 				return fmt.Errorf("unmarshal parameters: %w", err)
 			}
 
-			results, err := runner.Run(ctx, fileList)
-			if err != nil {
+			results, err := testRunner.Run(ctx, fileList)
+			var thresholdErr *runner.CoverageThresholdError
+			if err != nil && !errors.As(err, &thresholdErr) {
 				return fmt.Errorf("running test: %w", err)
 			}
 
-			outputter := output.Get(runner.Output, output.Options{NoColor: runner.NoColor, SuppressExceptions: runner.SuppressExceptions, Tracing: runner.Trace})
+			outputter := output.Get(testRunner.Output, output.Options{NoColor: testRunner.NoColor, SuppressExceptions: testRunner.SuppressExceptions, Tracing: testRunner.Trace})
 			if err := outputter.Output(results); err != nil {
 				return fmt.Errorf("output results: %w", err)
 			}
 
-			// When the no-fail parameter is set, there is no need to figure out the error code
-			// as we always want to return zero.
-			if runner.NoFail {
-				return nil
+			// A coverage below the threshold fails the run regardless of the
+			// results, so it is not hidden by the no-fail flag.
+			if thresholdErr != nil {
+				return fmt.Errorf("running test: %w", thresholdErr)
 			}
 
-			var exitCode int
-			if runner.FailOnWarn {
-				exitCode = output.ExitCodeFailOnWarn(results)
-			} else {
-				exitCode = output.ExitCode(results)
+			// The results have already been written, so the exit error
+			// only sets the exit code and is not printed.
+			if exitCode := testRunner.ExitCode(results); exitCode != 0 {
+				cmd.SilenceErrors = true
+				return &runner.ExitError{Code: exitCode}
 			}
 
-			os.Exit(exitCode)
 			return nil
 		},
 	}
@@ -136,6 +167,26 @@ func NewTestCommand(ctx context.Context) *cobra.Command {
 
 	cmd.Flags().BoolP("trace", "", false, "Enable more verbose trace output for Rego queries")
 	cmd.Flags().BoolP("combine", "", false, "Combine all config files to be evaluated together")
+	cmd.Flags().Bool("continue-on-error", false, "Report files that fail to parse as errors and continue testing the remaining files")
+	cmd.Flags().Bool("data-nested", false, "Place the data in subdirectories of a data path at the path of their directory")
+	cmd.Flags().Bool("data-prefix", false, "Root the data of each data path at the name of its directory")
+	cmd.Flags().Bool("detect-format", false, "Detect the format of files with an unknown or missing extension, and of stdin, from their contents")
+	cmd.Flags().Bool("expand-args", false, "Expand ARG and ENV references in Dockerfiles")
+	cmd.Flags().Bool("follow-modules", false, "Load Terraform modules with a local source when using the terraform parser")
+	cmd.Flags().Bool("gitignore", false, "Ignore the paths listed in .gitignore files when testing directories")
+	cmd.Flags().Bool("hcl2-ranges", false, "Add the source range of each HCL2 block under the __range key")
+
+	cmd.Flags().Bool("coverage", false, "Report the lines of the policies that were evaluated, per policy and overall")
+	cmd.Flags().String("coverage-format", runner.CoverageJSON, fmt.Sprintf("Format of the coverage report - valid options are: %s, %s, %s", runner.CoverageJSON, runner.CoverageCobertura, runner.CoverageLCOV))
+	cmd.Flags().String("coverage-file", "", "File to write the coverage report to, instead of stderr")
+	cmd.Flags().Float64("coverage-threshold", 0, "Fail when the coverage of the policies, as a percentage, is below the threshold")
+
+	cmd.Flags().Bool("profile", false, "Report the slowest expressions and rules of the policies, aggregated across all of the files")
+	cmd.Flags().String("profile-format", runner.ProfileTable, fmt.Sprintf("Format of the profile - valid options are: %s, %s", runner.ProfileTable, runner.ProfileJSON))
+	cmd.Flags().Int("profile-limit", 10, "Number of expressions, rules and evaluations in the profile, or 0 to include all of them")
+
+	cmd.Flags().Duration("timeout", 0, "Maximum duration of the whole test run (ex: 5m), after which no results are returned")
+	cmd.Flags().Duration("query-timeout", 0, "Maximum duration of the evaluation of a single rule against a file, after which an error is reported for the file")
 
 	cmd.Flags().String("ignore", "", "A regex pattern which can be used for ignoring paths")
 	cmd.Flags().String("parser", "", fmt.Sprintf("Parser to use to parse the configurations. Valid parsers: %s", parser.Parsers()))
@@ -145,7 +196,14 @@ func NewTestCommand(ctx context.Context) *cobra.Command {
 	cmd.Flags().StringSliceP("policy", "p", []string{"policy"}, "Path to the Rego policy files directory")
 	cmd.Flags().StringSliceP("update", "u", []string{}, "A list of URLs can be provided to the update flag, which will download before the tests run")
 	cmd.Flags().StringSliceP("namespace", "n", []string{"main"}, "Test policies in a specific namespace")
-	cmd.Flags().StringSliceP("data", "d", []string{}, "A list of paths from which data for the rego policies will be recursively loaded")
+	cmd.Flags().String("capabilities", "", "Path to an OPA capabilities file that lists the builtins the policies may use")
+	cmd.Flags().Bool("strict-builtins", false, "Forbid builtins that access the network or are nondeterministic, such as http.send and time.now_ns")
+	cmd.Flags().StringSlice("runtime-env", []string{}, "Patterns of the environment variables that are exposed to the policies through opa.runtime() (ex: CONFTEST_*)")
+	cmd.Flags().StringSliceP("data", "d", []string{}, "A list of paths from which data for the rego policies will be recursively loaded, optionally rooted at a prefix (path:prefix)")
+	cmd.Flags().StringSlice("include", []string{}, "Only test the files in directories that match the given gitignore-style patterns")
+	cmd.Flags().StringSlice("param", []string{}, "Parameters (KEY=VALUE) that are available to the policies under data.conftest.params")
+	cmd.Flags().StringSlice("param-file", []string{}, "Files with parameters that are available to the policies under data.conftest.params")
+	cmd.Flags().StringSlice("build-arg", []string{}, "Build arguments (KEY=VALUE) to use when expanding Dockerfiles")
 
 	return &cmd
 }
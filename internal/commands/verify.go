@@ -2,11 +2,11 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
 
-	"github.com/open-policy-agent/conftest/internal/runner"
 	"github.com/open-policy-agent/conftest/output"
+	"github.com/open-policy-agent/conftest/runner"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -41,7 +41,11 @@ people:
   - 18
   - 21
 
-The data is made available under 'import data.people'.
+The data is made available under 'import data.people'. The data of a path can 
+be rooted at a prefix with the 'path:prefix' syntax, or at the name of its 
+directory with the '--data-prefix' flag, e.g.:
+
+	$ conftest verify --data <data-directory>:company
 
 As with the test command, verify supports the '--output' flag to specify the type, e.g.:
 
@@ -49,6 +53,14 @@ As with the test command, verify supports the '--output' flag to specify the typ
 
 For a full list of available output types, see the use of the '--output' flag.
 
+The '--coverage' flag reports which lines of the policies were evaluated by the
+tests, per policy and overall. Unit tests themselves are not part of the report.
+The report is written to stderr, or to the file given with the '--coverage-file'
+flag, as JSON, Cobertura or lcov. With the '--coverage-threshold' flag, the
+verification fails when the coverage is too low, e.g.
+
+	$ conftest verify --coverage --coverage-threshold 80
+
 When debugging policies it can be useful to use a more verbose policy evaluation output. By using the '--trace' flag
 the output will include a detailed trace of how the policy was evaluated, e.g.
 
@@ -63,7 +75,7 @@ func NewVerifyCommand(ctx context.Context) *cobra.Command {
 		Short: "Verify Rego unit tests",
 		Long:  verifyDesc,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			flagNames := []string{"data", "no-color", "output", "policy", "trace"}
+			flagNames := []string{"capabilities", "coverage", "coverage-file", "coverage-format", "coverage-threshold", "data", "data-nested", "data-prefix", "no-color", "output", "policy", "runtime-env", "strict-builtins", "timeout", "trace"}
 			for _, name := range flagNames {
 				if err := viper.BindPFlag(name, cmd.Flags().Lookup(name)); err != nil {
 					return fmt.Errorf("bind flag: %w", err)
@@ -73,36 +85,55 @@ func NewVerifyCommand(ctx context.Context) *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			var runner runner.VerifyRunner
-			if err := viper.Unmarshal(&runner); err != nil {
+			var verifyRunner runner.VerifyRunner
+			if err := viper.Unmarshal(&verifyRunner); err != nil {
 				return fmt.Errorf("unmarshal parameters: %w", err)
 			}
 
-			results, err := runner.Run(ctx)
-			if err != nil {
+			results, err := verifyRunner.Run(ctx)
+			var thresholdErr *runner.CoverageThresholdError
+			if err != nil && !errors.As(err, &thresholdErr) {
 				return fmt.Errorf("running verification: %w", err)
 			}
 
-			outputter := output.Get(runner.Output, output.Options{NoColor: runner.NoColor, Tracing: runner.Trace, ShowSkipped: true})
+			outputter := output.Get(verifyRunner.Output, output.Options{NoColor: verifyRunner.NoColor, Tracing: verifyRunner.Trace, ShowSkipped: true})
 			if err := outputter.Output(results); err != nil {
 				return fmt.Errorf("output results: %w", err)
 			}
 
-			exitCode := output.ExitCode(results)
-			if exitCode > 0 {
-				os.Exit(exitCode)
+			// A coverage below the threshold fails the run, even when all
+			// of the tests pass.
+			if thresholdErr != nil {
+				return fmt.Errorf("running verification: %w", thresholdErr)
+			}
+
+			if exitCode := verifyRunner.ExitCode(results); exitCode != 0 {
+				cmd.SilenceErrors = true
+				return &runner.ExitError{Code: exitCode}
 			}
 
 			return nil
 		},
 	}
 
+	cmd.Flags().Bool("data-nested", false, "Place the data in subdirectories of a data path at the path of their directory")
+	cmd.Flags().Bool("data-prefix", false, "Root the data of each data path at the name of its directory")
 	cmd.Flags().Bool("no-color", false, "Disable color when printing")
 	cmd.Flags().Bool("trace", false, "Enable more verbose trace output for Rego queries")
 
+	cmd.Flags().Bool("coverage", false, "Report the lines of the policies that were evaluated, per policy and overall")
+	cmd.Flags().String("coverage-format", runner.CoverageJSON, fmt.Sprintf("Format of the coverage report - valid options are: %s, %s, %s", runner.CoverageJSON, runner.CoverageCobertura, runner.CoverageLCOV))
+	cmd.Flags().String("coverage-file", "", "File to write the coverage report to, instead of stderr")
+	cmd.Flags().Float64("coverage-threshold", 0, "Fail when the coverage of the policies, as a percentage, is below the threshold")
+
+	cmd.Flags().Duration("timeout", 0, "Maximum duration of the whole verification (ex: 5m), after which no results are returned")
+
 	cmd.Flags().StringP("output", "o", output.OutputStandard, fmt.Sprintf("Output format for conftest results - valid options are: %s", output.Outputs()))
 
-	cmd.Flags().StringSliceP("data", "d", []string{}, "A list of paths from which data for the rego policies will be recursively loaded")
+	cmd.Flags().String("capabilities", "", "Path to an OPA capabilities file that lists the builtins the policies may use")
+	cmd.Flags().Bool("strict-builtins", false, "Forbid builtins that access the network or are nondeterministic, such as http.send and time.now_ns")
+	cmd.Flags().StringSlice("runtime-env", []string{}, "Patterns of the environment variables that are exposed to the policies through opa.runtime() (ex: CONFTEST_*)")
+	cmd.Flags().StringSliceP("data", "d", []string{}, "A list of paths from which data for the rego policies will be recursively loaded, optionally rooted at a prefix (path:prefix)")
 	cmd.Flags().StringSliceP("policy", "p", []string{"policy"}, "Path to the Rego policy files directory")
 
 	return &cmd
@@ -0,0 +1,124 @@
+// Package gitignore matches paths against patterns that use the
+// syntax of .gitignore files.
+package gitignore
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Pattern is a compiled gitignore-style pattern.
+type Pattern struct {
+
+	// base is the directory that the pattern is relative to, using
+	// forward slashes. An empty base matches paths from any directory.
+	base string
+
+	regexp  *regexp.Regexp
+	dirOnly bool
+
+	// Negated is set for patterns that re-include the paths that were
+	// excluded by other patterns (ex: `!keep.json` in a .gitignore file).
+	Negated bool
+}
+
+// Match reports whether the pattern matches the given path, which uses
+// forward slashes and is relative to the same directory as the base of
+// the pattern.
+func (p Pattern) Match(relPath string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	if p.base != "" {
+		if !strings.HasPrefix(relPath, p.base+"/") {
+			return false
+		}
+
+		relPath = strings.TrimPrefix(relPath, p.base+"/")
+	}
+
+	return p.regexp.MatchString(relPath)
+}
+
+// Compile compiles a gitignore-style pattern that is relative to
+// the given base directory. A pattern that contains a slash, other than a
+// trailing slash, is matched against the full path. Otherwise it is matched
+// against the name of the file or directory at any depth.
+func Compile(base string, value string) (Pattern, error) {
+	p := Pattern{base: base}
+	if strings.HasSuffix(value, "/") {
+		p.dirOnly = true
+		value = strings.TrimSuffix(value, "/")
+	}
+
+	anchored := strings.Contains(value, "/")
+	value = strings.TrimPrefix(value, "/")
+
+	expression := globToRegexp(value)
+	if !anchored {
+		expression = "(.*/)?" + expression
+	}
+
+	compiled, err := regexp.Compile("^" + expression + "$")
+	if err != nil {
+		return Pattern{}, err
+	}
+
+	p.regexp = compiled
+	return p, nil
+}
+
+// globToRegexp converts a glob to a regular expression. In addition to the
+// syntax of path.Match, a leading `**/` matches in all directories, a trailing
+// `/**` matches everything inside and `/**/` matches zero or more directories.
+func globToRegexp(glob string) string {
+	var expression strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**") {
+				switch {
+				case strings.HasPrefix(glob[i:], "**/"):
+					expression.WriteString("(.*/)?")
+					i += 2
+				default:
+					expression.WriteString(".*")
+					i++
+				}
+				continue
+			}
+
+			expression.WriteString("[^/]*")
+
+		case '?':
+			expression.WriteString("[^/]")
+
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				expression.WriteString(`\[`)
+				continue
+			}
+
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			expression.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			expression.WriteString(regexp.QuoteMeta(string(glob[i])))
+
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return expression.String()
+}
@@ -0,0 +1,43 @@
+package gitignore
+
+import (
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{pattern: "*.yaml", path: "service.yaml", expected: true},
+		{pattern: "*.yaml", path: "k8s/service.yaml", expected: true},
+		{pattern: "*.yaml", path: "service.yml", expected: false},
+		{pattern: "/service.yaml", path: "k8s/service.yaml", expected: false},
+		{pattern: "k8s/*.yaml", path: "k8s/service.yaml", expected: true},
+		{pattern: "k8s/*.yaml", path: "k8s/prod/service.yaml", expected: false},
+		{pattern: "vendor/", path: "vendor", isDir: true, expected: true},
+		{pattern: "vendor/", path: "vendor", isDir: false, expected: false},
+		{pattern: "**/test/*.json", path: "a/b/test/data.json", expected: true},
+		{pattern: "k8s/**", path: "k8s/prod/service.yaml", expected: true},
+		{pattern: "a/**/b.yaml", path: "a/b.yaml", expected: true},
+		{pattern: "a/**/b.yaml", path: "a/x/y/b.yaml", expected: true},
+		{pattern: "service-?.yaml", path: "service-1.yaml", expected: true},
+		{pattern: "service-[!0-9].yaml", path: "service-1.yaml", expected: false},
+		{pattern: `\#file.yaml`, path: "#file.yaml", expected: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			p, err := Compile("", tt.pattern)
+			if err != nil {
+				t.Fatalf("compile pattern: %v", err)
+			}
+
+			if actual := p.Match(tt.path, tt.isDir); actual != tt.expected {
+				t.Errorf("expected match to be %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
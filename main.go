@@ -1,13 +1,20 @@
 package main
 
 import (
+	"errors"
 	"os"
 
 	"github.com/open-policy-agent/conftest/internal/commands"
+	"github.com/open-policy-agent/conftest/runner"
 )
 
 func main() {
 	if err := commands.NewDefaultCommand().Execute(); err != nil {
+		var exitErr *runner.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
+
 		os.Exit(1)
 	}
 }
@@ -140,6 +140,31 @@ func TestJSON(t *testing.T) {
 				``,
 			},
 		},
+		{
+			name: "An error",
+			input: []CheckResult{
+				{
+					FileName:  "examples/kubernetes/service.yaml",
+					Namespace: "-",
+					Errors:    []Result{{Message: "parser unmarshal: invalid"}},
+				},
+			},
+			expected: []string{
+				`[`,
+				`	{`,
+				`		"filename": "examples/kubernetes/service.yaml",`,
+				`		"namespace": "-",`,
+				`		"successes": 0,`,
+				`		"errors": [`,
+				`			{`,
+				`				"msg": "parser unmarshal: invalid"`,
+				`			}`,
+				`		]`,
+				`	}`,
+				`]`,
+				``,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -49,6 +49,16 @@ func (j *JUnit) Output(results []CheckResult) error {
 			tests = append(tests, &failingTest)
 		}
 
+		for _, resultError := range result.Errors {
+			errorTest := parser.Test{
+				Name:   getTestName(result.FileName, result.Namespace, resultError.Message),
+				Result: parser.FAIL,
+				Output: []string{resultError.Message},
+			}
+
+			tests = append(tests, &errorTest)
+		}
+
 		for _, skipped := range result.Skipped {
 			skippedTest := parser.Test{
 				Name:   getTestName(result.FileName, result.Namespace, skipped.Message),
@@ -66,6 +66,30 @@ func TestJUnit(t *testing.T) {
 				``,
 			},
 		},
+		{
+			name: "An error",
+			input: []CheckResult{
+				{
+					FileName:  "examples/kubernetes/service.yaml",
+					Namespace: "namespace",
+					Errors:    []Result{{Message: "parser unmarshal: invalid"}},
+				},
+			},
+			expected: []string{
+				`<?xml version="1.0" encoding="UTF-8"?>`,
+				`<testsuites>`,
+				`	<testsuite tests="1" failures="1" time="0.000" name="conftest">`,
+				`		<properties>`,
+				`			<property name="go.version" value="%s"></property>`,
+				`		</properties>`,
+				`		<testcase classname="conftest" name="examples/kubernetes/service.yaml - namespace - parser unmarshal: invalid" time="0.000">`,
+				`			<failure message="Failed" type="">parser unmarshal: invalid</failure>`,
+				`		</testcase>`,
+				`	</testsuite>`,
+				`</testsuites>`,
+				``,
+			},
+		},
 		{
 			name: "Failure with a long description",
 			input: []CheckResult{
@@ -69,6 +69,9 @@ func (q QueryResult) Passed() bool {
 // CheckResult describes the result of a conftest policy evaluation.
 // Errors produced by rego should be considered separate
 // from other classes of exceptions.
+//
+// Errors holds the errors that prevented the file from being evaluated,
+// such as the file failing to parse.
 type CheckResult struct {
 	FileName   string        `json:"filename"`
 	Namespace  string        `json:"namespace"`
@@ -77,17 +80,32 @@ type CheckResult struct {
 	Warnings   []Result      `json:"warnings,omitempty"`
 	Failures   []Result      `json:"failures,omitempty"`
 	Exceptions []Result      `json:"exceptions,omitempty"`
+	Errors     []Result      `json:"errors,omitempty"`
 	Queries    []QueryResult `json:"queries,omitempty"`
 }
 
+// ExitCodeError is the exit code that is returned when at least one of the
+// files could not be evaluated. It takes precedence over the other exit codes
+// as the results are incomplete.
+const ExitCodeError = 3
+
 // ExitCode returns the exit code that should be returned
 // given all of the returned results.
 func ExitCode(results []CheckResult) int {
 	var hasFailure bool
+	var hasError bool
 	for _, result := range results {
 		if len(result.Failures) > 0 {
 			hasFailure = true
 		}
+
+		if len(result.Errors) > 0 {
+			hasError = true
+		}
+	}
+
+	if hasError {
+		return ExitCodeError
 	}
 
 	if hasFailure {
@@ -103,6 +121,7 @@ func ExitCode(results []CheckResult) int {
 func ExitCodeFailOnWarn(results []CheckResult) int {
 	var hasFailure bool
 	var hasWarning bool
+	var hasError bool
 	for _, result := range results {
 		if len(result.Failures) > 0 {
 			hasFailure = true
@@ -111,6 +130,14 @@ func ExitCodeFailOnWarn(results []CheckResult) int {
 		if len(result.Warnings) > 0 {
 			hasWarning = true
 		}
+
+		if len(result.Errors) > 0 {
+			hasError = true
+		}
+	}
+
+	if hasError {
+		return ExitCodeError
 	}
 
 	if hasFailure {
@@ -17,6 +17,10 @@ func TestExitCode(t *testing.T) {
 		Skipped: []Result{{}},
 	}
 
+	resultError := CheckResult{
+		Errors: []Result{{}},
+	}
+
 	testCases := []struct {
 		results  []CheckResult
 		expected int
@@ -26,6 +30,7 @@ func TestExitCode(t *testing.T) {
 		{results: []CheckResult{skipped}, expected: 0},
 		{results: []CheckResult{failure}, expected: 1},
 		{results: []CheckResult{warning, failure}, expected: 1},
+		{results: []CheckResult{failure, resultError}, expected: 3},
 	}
 
 	for _, testCase := range testCases {
@@ -46,6 +51,10 @@ func TestExitCodeFailOnWarn(t *testing.T) {
 		Failures: []Result{{}},
 	}
 
+	resultError := CheckResult{
+		Errors: []Result{{}},
+	}
+
 	testCases := []struct {
 		results  []CheckResult
 		expected int
@@ -54,6 +63,7 @@ func TestExitCodeFailOnWarn(t *testing.T) {
 		{results: []CheckResult{warning}, expected: 1},
 		{results: []CheckResult{failure}, expected: 2},
 		{results: []CheckResult{warning, failure}, expected: 2},
+		{results: []CheckResult{warning, resultError}, expected: 3},
 	}
 
 	for _, testCase := range testCases {
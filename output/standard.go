@@ -54,6 +54,7 @@ func (s *Standard) Output(results []CheckResult) error {
 	var totalWarnings int
 	var totalSuccesses int
 	var totalSkipped int
+	var totalErrors int
 	for _, result := range results {
 		var indicator string
 		var namespace string
@@ -69,8 +70,14 @@ func (s *Standard) Output(results []CheckResult) error {
 			namespace = fmt.Sprintf("- %s -", result.Namespace)
 		}
 
+		for _, resultError := range result.Errors {
+			fmt.Fprintln(s.Writer, colorizer.Colorize("ERR", aurora.MagentaFg), indicator, namespace, resultError.Message)
+		}
+
+		totalErrors += len(result.Errors)
+
 		totalPolicies := result.Successes + len(result.Warnings) + len(result.Failures) + len(result.Exceptions) + len(result.Skipped)
-		if totalPolicies == 0 {
+		if totalPolicies == 0 && len(result.Errors) == 0 {
 			fmt.Fprintln(s.Writer, colorizer.Colorize("?", aurora.WhiteFg), indicator, namespace, "no policies found")
 			continue
 		}
@@ -130,8 +137,19 @@ func (s *Standard) Output(results []CheckResult) error {
 		outputText += fmt.Sprintf(", %v skipped", totalSkipped)
 	}
 
+	if totalErrors > 0 {
+		var pluralSuffixErrors string
+		if totalErrors != 1 {
+			pluralSuffixErrors = "s"
+		}
+
+		outputText += fmt.Sprintf(", %v error%s", totalErrors, pluralSuffixErrors)
+	}
+
 	var outputColor aurora.Color
-	if totalFailures > 0 {
+	if totalErrors > 0 {
+		outputColor = aurora.MagentaFg
+	} else if totalFailures > 0 {
 		outputColor = aurora.RedFg
 	} else if totalWarnings > 0 {
 		outputColor = aurora.YellowFg
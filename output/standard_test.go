@@ -89,6 +89,28 @@ func TestStandard(t *testing.T) {
 				"",
 			},
 		},
+		{
+			name: "records errors",
+			input: []CheckResult{
+				{
+					FileName:  "foo.yaml",
+					Namespace: "-",
+					Errors:    []Result{{Message: "parser unmarshal: invalid"}},
+				},
+				{
+					FileName:  "bar.yaml",
+					Namespace: "namespace",
+					Failures:  []Result{{Message: "first failure"}},
+				},
+			},
+			expected: []string{
+				"ERR - foo.yaml - parser unmarshal: invalid",
+				"FAIL - bar.yaml - namespace - first failure",
+				"",
+				"1 test, 0 passed, 0 warnings, 1 failure, 0 exceptions, 1 error",
+				"",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -47,6 +47,10 @@ func (t *Table) Output(checkResults []CheckResult) error {
 		for _, result := range checkResult.Failures {
 			tableData = append(tableData, []string{"failure", checkResult.FileName, checkResult.Namespace, result.Message})
 		}
+
+		for _, result := range checkResult.Errors {
+			tableData = append(tableData, []string{"error", checkResult.FileName, checkResult.Namespace, result.Message})
+		}
 	}
 
 	if len(tableData) > 0 {
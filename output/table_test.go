@@ -43,6 +43,24 @@ func TestTable(t *testing.T) {
 				``,
 			},
 		},
+		{
+			name: "An error",
+			input: []CheckResult{
+				{
+					FileName:  "examples/kubernetes/service.yaml",
+					Namespace: "-",
+					Errors:    []Result{{Message: "parser unmarshal: invalid"}},
+				},
+			},
+			expected: []string{
+				`+--------+----------------------------------+-----------+---------------------------+`,
+				`| RESULT |               FILE               | NAMESPACE |          MESSAGE          |`,
+				`+--------+----------------------------------+-----------+---------------------------+`,
+				`| error  | examples/kubernetes/service.yaml | -         | parser unmarshal: invalid |`,
+				`+--------+----------------------------------+-----------+---------------------------+`,
+				``,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -37,7 +37,7 @@ func (t *TAP) Output(checkResults []CheckResult) error {
 			namespace = fmt.Sprintf("- %s -", result.Namespace)
 		}
 
-		totalTests := result.Successes + len(result.Failures) + len(result.Warnings) + len(result.Exceptions) + len(result.Skipped)
+		totalTests := result.Successes + len(result.Failures) + len(result.Warnings) + len(result.Exceptions) + len(result.Skipped) + len(result.Errors)
 		if totalTests == 0 {
 			return nil
 		}
@@ -45,6 +45,14 @@ func (t *TAP) Output(checkResults []CheckResult) error {
 		counter := 1
 		fmt.Fprintf(t.Writer, "1..%d\n", totalTests)
 
+		if len(result.Errors) > 0 {
+			fmt.Fprintln(t.Writer, "# errors")
+			for _, resultError := range result.Errors {
+				fmt.Fprintf(t.Writer, "not ok %v %v %v %v\n", counter, indicator, namespace, resultError.Message)
+				counter++
+			}
+		}
+
 		for _, failure := range result.Failures {
 			fmt.Fprintf(t.Writer, "not ok %v %v %v %v\n", counter, indicator, namespace, failure.Message)
 			counter++
@@ -73,6 +73,22 @@ func TestTAP(t *testing.T) {
 				"",
 			},
 		},
+		{
+			name: "records errors",
+			input: []CheckResult{
+				{
+					FileName:  "examples/kubernetes/service.yaml",
+					Namespace: "-",
+					Errors:    []Result{{Message: "parser unmarshal: invalid"}},
+				},
+			},
+			expected: []string{
+				"1..1",
+				"# errors",
+				"not ok 1 - examples/kubernetes/service.yaml - parser unmarshal: invalid",
+				"",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,210 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ghodss/yaml"
+	"github.com/go-ini/ini"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/open-policy-agent/conftest/parser/docker"
+)
+
+// detector reports whether the contents are written in the format
+// of the parser with the given name.
+type detector struct {
+	name   string
+	detect func(contents []byte) bool
+}
+
+// detectors are checked in order, from the formats that are the least
+// ambiguous to the formats that accept almost any input. The first
+// detector that matches determines the format of the contents.
+var detectors = []detector{
+	{JSON, isJSON},
+	{XML, isXML},
+	{Dockerfile, isDockerfile},
+	{TOML, isTOML},
+	{HCL2, isHCL2},
+	{INI, isINI},
+	{YAML, isYAML},
+}
+
+// Detect returns the name of the parser for the given contents by inspecting
+// the contents themselves. The formats that can be detected are, in order of
+// confidence: JSON, XML, Dockerfile, TOML, HCL2, INI and YAML.
+func Detect(contents []byte) (string, error) {
+	if len(bytes.TrimSpace(contents)) == 0 {
+		return "", errors.New("detect format: empty contents")
+	}
+
+	for _, d := range detectors {
+		if d.detect(contents) {
+			return d.name, nil
+		}
+	}
+
+	return "", errors.New("detect format: unknown format")
+}
+
+func isJSON(contents []byte) bool {
+	trimmed := bytes.TrimSpace(contents)
+	if trimmed[0] != '{' && trimmed[0] != '[' {
+		return false
+	}
+
+	return json.Valid(trimmed)
+}
+
+func isXML(contents []byte) bool {
+	trimmed := bytes.TrimSpace(contents)
+	if trimmed[0] != '<' {
+		return false
+	}
+
+	var hasElement bool
+	decoder := xml.NewDecoder(bytes.NewReader(trimmed))
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return hasElement
+		}
+		if err != nil {
+			return false
+		}
+
+		if _, ok := token.(xml.StartElement); ok {
+			hasElement = true
+		}
+	}
+}
+
+// isDockerfile reports whether the first instruction is a FROM, or an ARG
+// that is used before the first FROM, and the contents are a valid Dockerfile.
+func isDockerfile(contents []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		instruction := strings.ToUpper(strings.Fields(line)[0])
+		if instruction != "FROM" && instruction != "ARG" {
+			return false
+		}
+
+		break
+	}
+
+	var parsed interface{}
+	return (&docker.Parser{}).Unmarshal(contents, &parsed) == nil
+}
+
+func isTOML(contents []byte) bool {
+	var parsed map[string]interface{}
+	if err := toml.Unmarshal(contents, &parsed); err != nil {
+		return false
+	}
+
+	return len(parsed) > 0
+}
+
+// isHCL2 reports whether the contents are valid HCL2 with at least one block.
+// Files that only contain attributes are too ambiguous to be detected as HCL2.
+func isHCL2(contents []byte) bool {
+	file, diags := hclsyntax.ParseConfig(contents, "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return false
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	return ok && len(body.Blocks) > 0
+}
+
+// isYAML reports whether the contents are YAML documents that contain
+// objects or lists. Almost any text is a valid YAML scalar, so documents
+// that are only scalars are not detected as YAML.
+func isYAML(contents []byte) bool {
+	for _, document := range separateDocuments(contents) {
+		if len(bytes.TrimSpace(document)) == 0 {
+			continue
+		}
+
+		var parsed interface{}
+		if err := yaml.Unmarshal(document, &parsed); err != nil {
+			return false
+		}
+
+		switch parsed.(type) {
+		case map[string]interface{}, []interface{}:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// isINI reports whether the contents are valid INI with at least one section.
+// Sections are required since keys such as `key: value` are also valid YAML.
+func isINI(contents []byte) bool {
+	cfg, err := ini.Load(contents)
+	if err != nil {
+		return false
+	}
+
+	for _, section := range cfg.Sections() {
+		if section.Name() != ini.DefaultSection {
+			return true
+		}
+	}
+
+	return false
+}
+
+func separateDocuments(contents []byte) [][]byte {
+	linebreak := "\n"
+	if bytes.Contains(contents, []byte("\r\n---\r\n")) {
+		linebreak = "\r\n"
+	}
+
+	return bytes.Split(contents, []byte(linebreak+"---"+linebreak))
+}
+
+// FileDetected returns true if the file at the given path can be parsed,
+// either because its parser is determined by the name or extension of the
+// file, or because its format can be detected from its contents.
+func FileDetected(path string) bool {
+	if FileRecognized(path) {
+		return true
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	_, err = Detect(contents)
+	return err == nil
+}
+
+// detectParser returns the name of the parser for the configuration at the
+// given path by inspecting its contents.
+func detectParser(path string, contents []byte) (string, error) {
+	name, err := Detect(contents)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contents string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "json object",
+			contents: `{"kind": "Service", "metadata": {"name": "test"}}`,
+			expected: JSON,
+		},
+		{
+			name:     "json array",
+			contents: "[\n  {\"name\": \"test\"}\n]",
+			expected: JSON,
+		},
+		{
+			name:     "xml",
+			contents: "<?xml version=\"1.0\"?>\n<project><name>test</name></project>",
+			expected: XML,
+		},
+		{
+			name:     "dockerfile",
+			contents: "# syntax=docker/dockerfile:1\nFROM alpine:3.13\nRUN apk add curl\n",
+			expected: Dockerfile,
+		},
+		{
+			name:     "dockerfile with arg before from",
+			contents: "ARG VERSION=3.13\nFROM alpine:${VERSION}\n",
+			expected: Dockerfile,
+		},
+		{
+			name:     "toml",
+			contents: "title = \"example\"\n\n[owner]\nname = \"test\"\n",
+			expected: TOML,
+		},
+		{
+			name:     "hcl2",
+			contents: "resource \"aws_s3_bucket\" \"bucket\" {\n  acl = \"private\"\n}\n",
+			expected: HCL2,
+		},
+		{
+			name:     "yaml",
+			contents: "apiVersion: v1\nkind: Service\nmetadata:\n  name: test\n",
+			expected: YAML,
+		},
+		{
+			name:     "yaml with multiple documents",
+			contents: "kind: Service\n---\nkind: Deployment\n",
+			expected: YAML,
+		},
+		{
+			name:     "ini",
+			contents: "[server]\nhttp_port = 3000\nprotocol = http\n",
+			expected: INI,
+		},
+		{
+			name:     "plain text",
+			contents: "this is not a configuration file\n",
+			wantErr:  true,
+		},
+		{
+			name:     "empty",
+			contents: "  \n",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := Detect([]byte(tt.contents))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if actual != tt.expected {
+				t.Errorf("expected parser %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseConfigurationsWithParsers_DetectFormat(t *testing.T) {
+	dir := t.TempDir()
+
+	extensionless := filepath.Join(dir, "service")
+	if err := ioutil.WriteFile(extensionless, []byte(`{"kind": "Service"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	unknown := filepath.Join(dir, "settings.conf")
+	if err := ioutil.WriteFile(unknown, []byte("[server]\nprotocol = http\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	known := filepath.Join(dir, "settings.toml")
+	if err := ioutil.WriteFile(known, []byte("port = 80\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []string{extensionless, unknown, known}
+	_, parsers, err := ParseConfigurationsWithParsers(files, Options{DetectFormat: true})
+	if err != nil {
+		t.Fatalf("parse configurations: %v", err)
+	}
+
+	expected := map[string]string{
+		extensionless: JSON,
+		unknown:       INI,
+		known:         TOML,
+	}
+
+	if !reflect.DeepEqual(parsers, expected) {
+		t.Errorf("expected parsers %v, got %v", expected, parsers)
+	}
+
+	if _, err := ParseConfigurations([]string{unknown}); err == nil {
+		t.Error("expected an error for an unknown extension without format detection")
+	}
+}
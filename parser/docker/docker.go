@@ -4,13 +4,23 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 	"github.com/moby/buildkit/frontend/dockerfile/parser"
 )
 
 // Parser is a Dockerfile parser.
-type Parser struct{}
+type Parser struct {
+
+	// Expand enables the expansion of ARG and ENV references. The expanded
+	// values are set on the Expanded field of each command.
+	Expand bool
+
+	// BuildArgs are the values of the build arguments (ex: `--build-arg`)
+	// used when expanding references. They take precedence over ARG defaults.
+	BuildArgs map[string]string
+}
 
 // Command represents a command in a Dockerfile.
 type Command struct {
@@ -27,16 +37,59 @@ type Command struct {
 	// Any flags such as `--from=...` for `COPY`.
 	Flags []string
 
+	// ParsedFlags holds the flags of the command keyed by their name
+	// (ex: `from` for `--from=builder`). Flags that do not have a value
+	// are set to `true`. When a flag is repeated, the last value is kept.
+	ParsedFlags map[string]string
+
 	// The contents of the command (ex: `ubuntu:xenial`)
 	Value []string
 
+	// KeyValues holds the key-value pairs of ENV, LABEL and ARG commands.
+	// An ARG without a default value has an empty value.
+	KeyValues map[string]string
+
+	// Heredocs holds the heredocs of RUN, COPY and ADD commands
+	// in the order they appear in the command.
+	Heredocs []Heredoc
+
 	// Stage indicates which stage the command is found in a multistage docker build
 	Stage int
+
+	// StageName is the name given to the stage with `AS name`, if any.
+	StageName string
+
+	// BaseImage is the image the stage of the command is built from.
+	BaseImage string
+
+	// Expanded holds the values of the command after ARG and ENV references
+	// have been expanded. It is only set when expansion is enabled.
+	Expanded *Expansion
+
+	// StartLine and EndLine are the lines in the Dockerfile where the
+	// command begins and ends, including any heredoc bodies.
+	StartLine int
+	EndLine   int
+}
+
+// Heredoc represents a heredoc (ex: `<<EOF`) used in a command.
+type Heredoc struct {
+
+	// Name is the delimiter of the heredoc (ex: `EOF`)
+	Name string
+
+	// Content is the body of the heredoc
+	Content string
 }
 
 // Unmarshal unmarshals Dockerfiles
 func (dp *Parser) Unmarshal(p []byte, v interface{}) error {
-	r := bytes.NewReader(p)
+	source, heredocs, err := extractHeredocs(p)
+	if err != nil {
+		return fmt.Errorf("extract heredocs: %w", err)
+	}
+
+	r := bytes.NewReader(source)
 	res, err := parser.Parse(r)
 	if err != nil {
 		return fmt.Errorf("parse dockerfile: %w", err)
@@ -45,6 +98,11 @@ func (dp *Parser) Unmarshal(p []byte, v interface{}) error {
 	var commands []Command
 	var stages []*instructions.Stage
 
+	var expander *expander
+	if dp.Expand {
+		expander = newExpander(res.EscapeToken, dp.BuildArgs)
+	}
+
 	for _, child := range res.AST.Children {
 		instr, err := instructions.ParseInstruction(child)
 		if err != nil {
@@ -62,17 +120,34 @@ func (dp *Parser) Unmarshal(p []byte, v interface{}) error {
 		for _, comment := range child.PrevComment {
 			cmd := Command{
 				Cmd:   "comment",
-				Stage: currentStage(stages),
 				Value: []string{comment},
 			}
+			setStage(&cmd, stages)
 
 			commands = append(commands, cmd)
 		}
 
 		cmd := Command{
-			Cmd:   child.Value,
-			Flags: child.Flags,
-			Stage: currentStage(stages),
+			Cmd:         child.Value,
+			Flags:       child.Flags,
+			ParsedFlags: parseFlags(child.Flags),
+			KeyValues:   keyValues(instr),
+			StartLine:   child.StartLine,
+			EndLine:     child.EndLine,
+		}
+		setStage(&cmd, stages)
+
+		// The body of a heredoc is not part of the parsed node, so the
+		// command ends where its last heredoc ends.
+		for _, heredoc := range heredocs {
+			if heredoc.line < child.StartLine || heredoc.line > child.EndLine {
+				continue
+			}
+
+			cmd.Heredocs = append(cmd.Heredocs, heredoc.Heredoc)
+			if heredoc.endLine > cmd.EndLine {
+				cmd.EndLine = heredoc.endLine
+			}
 		}
 
 		if child.Next != nil && len(child.Next.Children) > 0 {
@@ -85,6 +160,12 @@ func (dp *Parser) Unmarshal(p []byte, v interface{}) error {
 			cmd.Value = append(cmd.Value, n.Value)
 		}
 
+		if expander != nil {
+			if err := expander.expand(&cmd, instr); err != nil {
+				return fmt.Errorf("expand line %d: %w", cmd.StartLine, err)
+			}
+		}
+
 		commands = append(commands, cmd)
 	}
 
@@ -112,3 +193,69 @@ func currentStage(stages []*instructions.Stage) int {
 
 	return len(stages) - 1
 }
+
+// setStage sets the stage information of the command based on the
+// stages that have been found so far.
+func setStage(cmd *Command, stages []*instructions.Stage) {
+	cmd.Stage = currentStage(stages)
+	if len(stages) == 0 {
+		return
+	}
+
+	stage := stages[len(stages)-1]
+	cmd.StageName = stage.Name
+	cmd.BaseImage = stage.BaseName
+}
+
+// parseFlags parses flags in the form of `--name=value` or `--name`
+// into a map keyed by the name of the flag.
+func parseFlags(flags []string) map[string]string {
+	if len(flags) == 0 {
+		return nil
+	}
+
+	parsed := make(map[string]string)
+	for _, flag := range flags {
+		flag = strings.TrimPrefix(flag, "--")
+
+		parts := strings.SplitN(flag, "=", 2)
+		if len(parts) == 1 {
+			parsed[parts[0]] = "true"
+			continue
+		}
+
+		parsed[parts[0]] = parts[1]
+	}
+
+	return parsed
+}
+
+// keyValues returns the key-value pairs of ENV, LABEL and ARG instructions.
+// Any other instruction returns nil.
+func keyValues(instr interface{}) map[string]string {
+	var pairs instructions.KeyValuePairs
+	switch c := instr.(type) {
+	case *instructions.EnvCommand:
+		pairs = c.Env
+	case *instructions.LabelCommand:
+		pairs = c.Labels
+	case *instructions.ArgCommand:
+		for _, arg := range c.Args {
+			pair := instructions.KeyValuePair{Key: arg.Key}
+			if arg.Value != nil {
+				pair.Value = *arg.Value
+			}
+
+			pairs = append(pairs, pair)
+		}
+	default:
+		return nil
+	}
+
+	values := make(map[string]string)
+	for _, pair := range pairs {
+		values[pair.Key] = pair.Value
+	}
+
+	return values
+}
@@ -68,3 +68,113 @@ RUN go build -o conftest`
 		t.Errorf("expected command to be in stage 1, not stage: %v", stage)
 	}
 }
+
+func TestParser_Unmarshal_Instructions(t *testing.T) {
+	parser := Parser{}
+
+	sample := `FROM golang:1.16-alpine AS builder
+ENV CGO_ENABLED=0 GOOS=linux
+ARG VERSION
+RUN <<EOF
+go build -o /conftest
+EOF
+
+FROM alpine:3.13
+LABEL maintainer="conftest"
+COPY --from=builder --chown=nobody /conftest /`
+
+	var input [][]Command
+	if err := parser.Unmarshal([]byte(sample), &input); err != nil {
+		t.Fatalf("parser should not have thrown an error: %v", err)
+	}
+
+	commands := input[0]
+	if len(commands) != 7 {
+		t.Fatalf("expected 7 commands, got %v", len(commands))
+	}
+
+	env := commands[1]
+	if env.KeyValues["CGO_ENABLED"] != "0" || env.KeyValues["GOOS"] != "linux" {
+		t.Errorf("unexpected env key values: %v", env.KeyValues)
+	}
+
+	arg := commands[2]
+	if value, ok := arg.KeyValues["VERSION"]; !ok || value != "" {
+		t.Errorf("unexpected arg key values: %v", arg.KeyValues)
+	}
+
+	run := commands[3]
+	if run.StageName != "builder" || run.BaseImage != "golang:1.16-alpine" {
+		t.Errorf("unexpected stage for run command: %v (%v)", run.StageName, run.BaseImage)
+	}
+
+	if len(run.Heredocs) != 1 || run.Heredocs[0].Name != "EOF" || run.Heredocs[0].Content != "go build -o /conftest\n" {
+		t.Errorf("unexpected heredocs: %v", run.Heredocs)
+	}
+
+	if run.StartLine != 4 || run.EndLine != 6 {
+		t.Errorf("expected run command on lines 4-6, got %v-%v", run.StartLine, run.EndLine)
+	}
+
+	copy := commands[6]
+	if copy.Stage != 1 || copy.StageName != "" || copy.BaseImage != "alpine:3.13" {
+		t.Errorf("unexpected stage for copy command: %v %v (%v)", copy.Stage, copy.StageName, copy.BaseImage)
+	}
+
+	if copy.ParsedFlags["from"] != "builder" || copy.ParsedFlags["chown"] != "nobody" {
+		t.Errorf("unexpected parsed flags: %v", copy.ParsedFlags)
+	}
+}
+
+func TestParser_Unmarshal_UnterminatedHeredoc(t *testing.T) {
+	parser := Parser{}
+
+	sample := `FROM foo
+RUN <<EOF
+echo hello`
+
+	var input interface{}
+	if err := parser.Unmarshal([]byte(sample), &input); err == nil {
+		t.Error("expected an error for an unterminated heredoc")
+	}
+}
+
+func TestParser_Unmarshal_Expand(t *testing.T) {
+	parser := Parser{
+		Expand:    true,
+		BuildArgs: map[string]string{"TAG": "3.13"},
+	}
+
+	sample := `ARG BASE=alpine
+ARG TAG=latest
+FROM ${BASE}:${TAG}
+ARG BASE
+ENV HOME=/home/${BASE}
+WORKDIR $HOME`
+
+	var input [][]Command
+	if err := parser.Unmarshal([]byte(sample), &input); err != nil {
+		t.Fatalf("parser should not have thrown an error: %v", err)
+	}
+
+	commands := input[0]
+
+	from := commands[2]
+	if from.BaseImage != "${BASE}:${TAG}" {
+		t.Errorf("expected raw base image to be kept, got %v", from.BaseImage)
+	}
+
+	if from.Expanded.BaseImage != "alpine:3.13" {
+		t.Errorf("unexpected expanded base image: %v", from.Expanded.BaseImage)
+	}
+
+	env := commands[4]
+	if env.Expanded.KeyValues["HOME"] != "/home/alpine" {
+		t.Errorf("unexpected expanded env: %v", env.Expanded.KeyValues)
+	}
+
+	workdir := commands[5]
+	if workdir.Value[0] != "$HOME" || workdir.Expanded.Value[0] != "/home/alpine" {
+		t.Errorf("unexpected workdir values: %v %v", workdir.Value, workdir.Expanded.Value)
+	}
+}
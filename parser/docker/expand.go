@@ -0,0 +1,231 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+	"github.com/moby/buildkit/frontend/dockerfile/shell"
+)
+
+// expandableCommands are the commands whose values are expanded
+// by the Docker builder.
+var expandableCommands = map[string]bool{
+	"from":       true,
+	"arg":        true,
+	"env":        true,
+	"label":      true,
+	"add":        true,
+	"copy":       true,
+	"workdir":    true,
+	"user":       true,
+	"volume":     true,
+	"expose":     true,
+	"stopsignal": true,
+}
+
+// Expansion holds the values of a command after ARG and ENV references
+// have been expanded. BaseImage is only set for FROM commands.
+type Expansion struct {
+	Value       []string
+	KeyValues   map[string]string
+	ParsedFlags map[string]string
+	BaseImage   string
+}
+
+// expander keeps track of the ARG and ENV values that are in scope
+// while walking through the commands of a Dockerfile.
+type expander struct {
+	lex       *shell.Lex
+	buildArgs map[string]string
+
+	// metaArgs are the ARGs declared before the first FROM. They are
+	// available to FROM and to stages that redeclare them.
+	metaArgs map[string]string
+
+	inStage bool
+	args    map[string]string
+	env     map[string]string
+}
+
+func newExpander(escapeToken rune, buildArgs map[string]string) *expander {
+	return &expander{
+		lex:       shell.NewLex(escapeToken),
+		buildArgs: buildArgs,
+		metaArgs:  make(map[string]string),
+		args:      make(map[string]string),
+		env:       make(map[string]string),
+	}
+}
+
+// expand sets the expanded values of the given command and updates the
+// values that are in scope for the commands that follow it.
+func (e *expander) expand(cmd *Command, instr interface{}) error {
+	if !expandableCommands[cmd.Cmd] {
+		return nil
+	}
+
+	if stage, ok := instr.(*instructions.Stage); ok {
+		e.inStage = true
+		e.args = make(map[string]string)
+		e.env = make(map[string]string)
+
+		baseImage, err := e.lex.ProcessWordWithMap(stage.BaseName, e.metaArgs)
+		if err != nil {
+			return fmt.Errorf("expand base image: %w", err)
+		}
+
+		values, err := e.process(cmd.Value, e.metaArgs)
+		if err != nil {
+			return fmt.Errorf("expand from: %w", err)
+		}
+
+		cmd.Expanded = &Expansion{Value: values, BaseImage: baseImage}
+		return nil
+	}
+
+	scope := e.scope()
+	values, err := e.process(cmd.Value, scope)
+	if err != nil {
+		return fmt.Errorf("expand %s: %w", cmd.Cmd, err)
+	}
+
+	flags := make(map[string]string)
+	for name, value := range cmd.ParsedFlags {
+		flags[name], err = e.lex.ProcessWordWithMap(value, scope)
+		if err != nil {
+			return fmt.Errorf("expand flag %s: %w", name, err)
+		}
+	}
+
+	cmd.Expanded = &Expansion{Value: values, ParsedFlags: flags}
+	if len(cmd.ParsedFlags) == 0 {
+		cmd.Expanded.ParsedFlags = nil
+	}
+
+	switch c := instr.(type) {
+	case *instructions.ArgCommand:
+		cmd.Expanded.KeyValues = make(map[string]string)
+		for _, arg := range c.Args {
+			value, ok, err := e.resolveArg(arg, scope)
+			if err != nil {
+				return fmt.Errorf("expand arg %s: %w", arg.Key, err)
+			}
+
+			if !ok {
+				continue
+			}
+
+			cmd.Expanded.KeyValues[arg.Key] = value
+			if e.inStage {
+				e.args[arg.Key] = value
+			} else {
+				e.metaArgs[arg.Key] = value
+			}
+		}
+
+	case *instructions.EnvCommand:
+		cmd.Expanded.KeyValues = make(map[string]string)
+		for _, env := range c.Env {
+			value, err := e.lex.ProcessWordWithMap(env.Value, scope)
+			if err != nil {
+				return fmt.Errorf("expand env %s: %w", env.Key, err)
+			}
+
+			cmd.Expanded.KeyValues[env.Key] = value
+			e.env[env.Key] = value
+		}
+
+	case *instructions.LabelCommand:
+		cmd.Expanded.KeyValues = make(map[string]string)
+		for _, label := range c.Labels {
+			value, err := e.lex.ProcessWordWithMap(label.Value, scope)
+			if err != nil {
+				return fmt.Errorf("expand label %s: %w", label.Key, err)
+			}
+
+			cmd.Expanded.KeyValues[label.Key] = value
+		}
+	}
+
+	return nil
+}
+
+// resolveArg returns the value of an ARG. Build arguments take precedence
+// over the default value of the ARG. An ARG in a stage without a default
+// value inherits the value of the ARG declared before the first FROM.
+func (e *expander) resolveArg(arg instructions.KeyValuePairOptional, scope map[string]string) (string, bool, error) {
+	if value, ok := e.buildArgs[arg.Key]; ok {
+		return value, true, nil
+	}
+
+	if arg.Value != nil {
+		value, err := e.lex.ProcessWordWithMap(*arg.Value, scope)
+		if err != nil {
+			return "", false, err
+		}
+
+		return value, true, nil
+	}
+
+	if e.inStage {
+		value, ok := e.metaArgs[arg.Key]
+		return value, ok, nil
+	}
+
+	return "", false, nil
+}
+
+// scope returns the values that can be referenced by the current command.
+// Values set by ENV take precedence over values set by ARG.
+func (e *expander) scope() map[string]string {
+	if !e.inStage {
+		return e.metaArgs
+	}
+
+	scope := make(map[string]string)
+	for key, value := range e.args {
+		scope[key] = value
+	}
+
+	for key, value := range e.env {
+		scope[key] = value
+	}
+
+	return scope
+}
+
+func (e *expander) process(words []string, scope map[string]string) ([]string, error) {
+	var values []string
+	for _, word := range words {
+		value, err := e.lex.ProcessWordWithMap(word, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// ParseBuildArgs parses build arguments in the form of KEY=VALUE. Similar to
+// `docker build`, a build argument without a value takes its value from the
+// environment and is skipped when the environment variable is not set.
+func ParseBuildArgs(args []string) map[string]string {
+	buildArgs := make(map[string]string)
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) == 2 {
+			buildArgs[parts[0]] = parts[1]
+			continue
+		}
+
+		if value, ok := os.LookupEnv(parts[0]); ok {
+			buildArgs[parts[0]] = value
+		}
+	}
+
+	return buildArgs
+}
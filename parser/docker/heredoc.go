@@ -0,0 +1,103 @@
+package docker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// heredocRegex matches heredoc markers such as <<EOF, <<-EOF, <<"EOF" and <<'EOF'.
+var heredocRegex = regexp.MustCompile(`(?:^|\s)<<(-?)(?:"([^"]+)"|'([^']+)'|([A-Za-z_][A-Za-z0-9_]*))`)
+
+// escapeRegex matches the escape parser directive (ex: # escape=`).
+var escapeRegex = regexp.MustCompile(`^#\s*escape\s*=\s*(\S)`)
+
+// heredoc is a heredoc along with the lines where it was found.
+type heredoc struct {
+	Heredoc
+
+	// line is the line that contains the heredoc marker.
+	line int
+
+	// endLine is the line that terminates the heredoc.
+	endLine int
+}
+
+// extractHeredocs removes the bodies of all heredocs found in the Dockerfile
+// and returns the remaining source together with the heredocs that were found.
+//
+// The version of the Dockerfile parser in use does not support heredocs, so their
+// bodies would otherwise be parsed as instructions. Each line of a heredoc body is
+// replaced with an empty line to keep the line numbers of the parsed nodes intact.
+func extractHeredocs(p []byte) ([]byte, []heredoc, error) {
+	lines := strings.SplitAfter(string(p), "\n")
+
+	escapeToken := `\`
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "#") {
+			break
+		}
+
+		if matches := escapeRegex.FindStringSubmatch(trimmed); matches != nil {
+			escapeToken = matches[1]
+		}
+	}
+
+	var heredocs []heredoc
+	var instruction string
+	var continuation bool
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if !continuation {
+			instruction = strings.ToUpper(strings.Fields(trimmed)[0])
+		}
+		continuation = strings.HasSuffix(trimmed, escapeToken)
+
+		if instruction != "RUN" && instruction != "COPY" && instruction != "ADD" {
+			continue
+		}
+
+		markerLine := i
+		for _, matches := range heredocRegex.FindAllStringSubmatch(lines[markerLine], -1) {
+			chomp := matches[1] == "-"
+			name := matches[2] + matches[3] + matches[4]
+
+			var content strings.Builder
+			terminated := false
+			for i = i + 1; i < len(lines); i++ {
+				line := strings.TrimRight(lines[i], "\r\n")
+				lines[i] = "\n"
+				if chomp {
+					line = strings.TrimLeft(line, "\t")
+				}
+
+				if line == name {
+					terminated = true
+					break
+				}
+
+				content.WriteString(line + "\n")
+			}
+
+			if !terminated {
+				return nil, nil, fmt.Errorf("unterminated heredoc %q on line %d", name, markerLine+1)
+			}
+
+			heredocs = append(heredocs, heredoc{
+				Heredoc: Heredoc{
+					Name:    name,
+					Content: content.String(),
+				},
+				line:    markerLine + 1,
+				endLine: i + 1,
+			})
+		}
+	}
+
+	return []byte(strings.Join(lines, "")), heredocs, nil
+}
@@ -3,24 +3,149 @@ package hcl2
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/tmccombs/hcl2json/convert"
 )
 
 // Parser is an HCL2 parser.
-type Parser struct{}
+type Parser struct {
+
+	// Filename is the name of the file that is being parsed. It is
+	// included in the errors and the ranges of the parsed blocks.
+	Filename string
+
+	// Ranges adds the source range of each block to the parsed
+	// output under the __range key.
+	Ranges bool
+}
+
+// Range is the location of a block in the source file.
+type Range struct {
+	Filename string `json:"filename"`
+	Start    Pos    `json:"start"`
+	End      Pos    `json:"end"`
+}
+
+// Pos is a position in the source file.
+type Pos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
 
 // Unmarshal unmarshals HCL files that are written using
 // version 2 of the HCL language.
-func (Parser) Unmarshal(p []byte, v interface{}) error {
-	hclBytes, err := convert.Bytes(p, "", convert.Options{})
+func (hp *Parser) Unmarshal(p []byte, v interface{}) error {
+	file, diags := hclsyntax.ParseConfig(p, hp.Filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return fmt.Errorf("parse config: %s", formatDiagnostics(diags))
+	}
+
+	hclBytes, err := convert.File(file, convert.Options{})
 	if err != nil {
-		return fmt.Errorf("convert to bytes: %w", err)
+		return fmt.Errorf("convert file: %w", err)
 	}
 
-	if err := json.Unmarshal(hclBytes, v); err != nil {
+	if !hp.Ranges {
+		if err := json.Unmarshal(hclBytes, v); err != nil {
+			return fmt.Errorf("unmarshal hcl2: %w", err)
+		}
+
+		return nil
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(hclBytes, &document); err != nil {
 		return fmt.Errorf("unmarshal hcl2: %w", err)
 	}
 
+	attachRanges(file.Body.(*hclsyntax.Body), document)
+
+	j, err := json.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("marshal hcl2 with ranges: %w", err)
+	}
+
+	if err := json.Unmarshal(j, v); err != nil {
+		return fmt.Errorf("unmarshal hcl2 with ranges: %w", err)
+	}
+
 	return nil
 }
+
+// formatDiagnostics formats the error diagnostics in the
+// form of file:line:col: summary; detail.
+func formatDiagnostics(diags hcl.Diagnostics) string {
+	var errs []string
+	for _, diag := range diags {
+		if diag.Severity != hcl.DiagError {
+			continue
+		}
+
+		message := diag.Summary
+		if diag.Detail != "" {
+			message += "; " + diag.Detail
+		}
+
+		if diag.Subject != nil {
+			message = fmt.Sprintf("%s:%d:%d: %s", diag.Subject.Filename, diag.Subject.Start.Line, diag.Subject.Start.Column, message)
+		}
+
+		errs = append(errs, message)
+	}
+
+	return strings.Join(errs, ", ")
+}
+
+// attachRanges adds the range of each block in the body to the matching
+// object in the converted output. The converted output nests blocks by their
+// type and labels, and combines blocks with the same type and labels into a list.
+func attachRanges(body *hclsyntax.Body, out map[string]interface{}) {
+	occurrences := make(map[string]int)
+	for _, block := range body.Blocks {
+		keys := append([]string{block.Type}, block.Labels...)
+
+		parent := out
+		for _, key := range keys[:len(keys)-1] {
+			parent, _ = parent[key].(map[string]interface{})
+			if parent == nil {
+				break
+			}
+		}
+
+		if parent == nil {
+			continue
+		}
+
+		id := strings.Join(keys, ".")
+		index := occurrences[id]
+		occurrences[id]++
+
+		var blockBody map[string]interface{}
+		switch value := parent[keys[len(keys)-1]].(type) {
+		case map[string]interface{}:
+			if index == 0 {
+				blockBody = value
+			}
+		case []interface{}:
+			if index < len(value) {
+				blockBody, _ = value[index].(map[string]interface{})
+			}
+		}
+
+		if blockBody == nil {
+			continue
+		}
+
+		blockRange := block.Range()
+		blockBody["__range"] = Range{
+			Filename: blockRange.Filename,
+			Start:    Pos{Line: blockRange.Start.Line, Column: blockRange.Start.Column},
+			End:      Pos{Line: blockRange.End.Line, Column: blockRange.End.Column},
+		}
+
+		attachRanges(block.Body, blockBody)
+	}
+}
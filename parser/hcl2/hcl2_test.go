@@ -0,0 +1,63 @@
+package hcl2
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParser_Unmarshal_Error(t *testing.T) {
+	parser := Parser{Filename: "main.tf"}
+
+	sample := `resource "aws_instance" "web" {
+  ami =
+}`
+
+	var input interface{}
+	err := parser.Unmarshal([]byte(sample), &input)
+	if err == nil {
+		t.Fatal("expected an error when parsing invalid HCL")
+	}
+
+	if !strings.Contains(err.Error(), "main.tf:2:8") {
+		t.Errorf("expected error to contain the position of the error, got: %v", err)
+	}
+}
+
+func TestParser_Unmarshal_Ranges(t *testing.T) {
+	parser := Parser{Filename: "main.tf", Ranges: true}
+
+	sample := `resource "aws_instance" "web" {
+  ami = "ami-123"
+
+  ebs_block_device {
+    device_name = "/dev/sdg"
+  }
+
+  ebs_block_device {
+    device_name = "/dev/sdh"
+  }
+}`
+
+	var input map[string]interface{}
+	if err := parser.Unmarshal([]byte(sample), &input); err != nil {
+		t.Fatalf("parser should not have thrown an error: %v", err)
+	}
+
+	instance := input["resource"].(map[string]interface{})["aws_instance"].(map[string]interface{})["web"].(map[string]interface{})
+	instanceRange := instance["__range"].(map[string]interface{})
+	if instanceRange["filename"] != "main.tf" {
+		t.Errorf("unexpected filename: %v", instanceRange["filename"])
+	}
+
+	start := instanceRange["start"].(map[string]interface{})
+	end := instanceRange["end"].(map[string]interface{})
+	if start["line"] != 1.0 || end["line"] != 11.0 {
+		t.Errorf("expected range to span lines 1-11, got %v-%v", start["line"], end["line"])
+	}
+
+	devices := instance["ebs_block_device"].([]interface{})
+	deviceRange := devices[1].(map[string]interface{})["__range"].(map[string]interface{})
+	if deviceRange["start"].(map[string]interface{})["line"] != 8.0 {
+		t.Errorf("expected second block to start on line 8, got %v", deviceRange["start"])
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/open-policy-agent/conftest/parser/ini"
 	"github.com/open-policy-agent/conftest/parser/json"
 	"github.com/open-policy-agent/conftest/parser/jsonnet"
+	"github.com/open-policy-agent/conftest/parser/terraform"
 	"github.com/open-policy-agent/conftest/parser/toml"
 	"github.com/open-policy-agent/conftest/parser/vcl"
 	"github.com/open-policy-agent/conftest/parser/xml"
@@ -42,6 +43,7 @@ const (
 	VCL        = "vcl"
 	XML        = "xml"
 	IGNORE     = "ignore"
+	Terraform  = "terraform"
 )
 
 // Parser defines all of the methods that every parser
@@ -50,107 +52,159 @@ type Parser interface {
 	Unmarshal(p []byte, v interface{}) error
 }
 
+// DirectoryParser defines a parser that is also able to parse
+// all of the files in a directory as a single configuration.
+type DirectoryParser interface {
+	Parser
+	UnmarshalDirectory(dir string, v interface{}) error
+}
+
+// Options configures how configurations are parsed.
+type Options struct {
+
+	// Parser is the name of the parser to use for all of the files.
+	// When empty, the parser is determined by the path of each file.
+	Parser string
+
+	// ExpandArgs enables the expansion of ARG and ENV references in Dockerfiles.
+	ExpandArgs bool
+
+	// BuildArgs are the build arguments used when expanding Dockerfiles.
+	BuildArgs map[string]string
+
+	// FollowModules loads Terraform modules that have a local source.
+	FollowModules bool
+
+	// Ranges adds the source range of each block to HCL2 configurations.
+	Ranges bool
+
+	// DetectFormat determines the parser from the contents of a file when
+	// its extension is unknown or missing, and for standard input.
+	DetectFormat bool
+
+	// ContinueOnError parses the remaining files when a file fails to parse.
+	// The files that failed to parse are left out of the configurations and
+	// their errors are returned together as ParseErrors.
+	ContinueOnError bool
+}
+
+// FileError is the error of a file that failed to parse.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// ParseErrors are the errors of all of the files that failed
+// to parse, in the order the files were given.
+type ParseErrors []*FileError
+
+func (e ParseErrors) Error() string {
+	var messages []string
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, ", ")
+}
+
+func init() {
+	Register(TOML, func() Parser { return &toml.Parser{} }, "toml")
+	Register(HCL1, func() Parser { return &hcl1.Parser{} }, "hcl1")
+	Register(HCL2, func() Parser { return &hcl2.Parser{} }, "hcl2", "tf", "tfvars")
+	Register(CUE, func() Parser { return &cue.Parser{} }, "cue")
+	Register(INI, func() Parser { return &ini.Parser{} }, "ini")
+	Register(HOCON, func() Parser { return &hocon.Parser{} }, "hocon")
+	Register(Dockerfile, func() Parser { return &docker.Parser{} }, "dockerfile")
+	Register(YAML, func() Parser { return &yaml.Parser{} }, "yaml", "yml")
+	Register(JSON, func() Parser { return &json.Parser{} }, "json")
+	Register(JSONNET, func() Parser { return &jsonnet.Parser{} }, "jsonnet")
+	Register(EDN, func() Parser { return &edn.Parser{} }, "edn")
+	Register(VCL, func() Parser { return &vcl.Parser{} }, "vcl")
+	Register(XML, func() Parser { return &xml.Parser{} }, "xml")
+	Register(IGNORE, func() Parser { return &ignore.Parser{} }, "ignore", "gitignore", "dockerignore")
+	Register(Terraform, func() Parser { return &terraform.Parser{} })
+
+	// A Dockerfile can either be a file named Dockerfile, be prefixed with
+	// Dockerfile, or have Dockerfile as its extension.
+	//
+	// For example: Dockerfile, Dockerfile.debug, dev.Dockerfile
+	RegisterPatterns(Dockerfile, "dockerfile", "dockerfile.*")
+}
+
 // New returns a new Parser.
 func New(parser string) (Parser, error) {
-	switch parser {
-	case TOML:
-		return &toml.Parser{}, nil
-	case CUE:
-		return &cue.Parser{}, nil
-	case INI:
-		return &ini.Parser{}, nil
-	case HOCON:
-		return &hocon.Parser{}, nil
-	case HCL1:
-		return &hcl1.Parser{}, nil
-	case HCL2:
-		return &hcl2.Parser{}, nil
-	case Dockerfile:
-		return &docker.Parser{}, nil
-	case YAML:
-		return &yaml.Parser{}, nil
-	case JSON:
-		return &json.Parser{}, nil
-	case JSONNET:
-		return &jsonnet.Parser{}, nil
-	case EDN:
-		return &edn.Parser{}, nil
-	case VCL:
-		return &vcl.Parser{}, nil
-	case XML:
-		return &xml.Parser{}, nil
-	case IGNORE:
-		return &ignore.Parser{}, nil
-	default:
-		return nil, fmt.Errorf("unknown parser: %v", parser)
+	factory, err := lookupName(parser)
+	if err != nil {
+		return nil, err
 	}
+
+	return factory(), nil
 }
 
 // NewFromPath returns a file parser based on the file type
 // that exists at the given path.
 func NewFromPath(path string) (Parser, error) {
+	name, err := parserFromPath(path, false)
+	if err != nil {
+		return nil, fmt.Errorf("new: %w", err)
+	}
+
+	return New(name)
+}
+
+// NameFromPath returns the name of the parser that is used for the
+// file at the given path.
+func NameFromPath(path string) (string, error) {
+	return parserFromPath(path, false)
+}
+
+// parserFromPath returns the name of the parser for the file at the given path.
+// When detect is true, an empty name is returned for standard input and for
+// files with an unknown or missing extension so that the format can be
+// detected from the contents instead.
+func parserFromPath(path string, detect bool) (string, error) {
 
 	// We use the YAML parser as the default when passing in configuration
 	// data through standard input. This can be overridden by using the parser flag.
 	if path == "-" {
-		return New(YAML)
+		if detect {
+			return "", nil
+		}
+
+		return YAML, nil
 	}
 
 	fileName := strings.ToLower(filepath.Base(path))
 
-	fileExtension := "yml"
+	var fileExtension string
 	if len(filepath.Ext(path)) > 0 {
 		fileExtension = strings.ToLower(filepath.Ext(path)[1:])
 	}
 
-	// A Dockerfile can either be a file named Dockerfile, be prefixed with
-	// Dockerfile, or have Dockerfile as its extension.
-	//
-	// For example: Dockerfile, Dockerfile.debug, dev.Dockerfile
-	if fileName == "dockerfile" || strings.HasPrefix(fileName, "dockerfile.") || fileExtension == "dockerfile" {
-		return New(Dockerfile)
-	}
-
-	if fileExtension == "yml" || fileExtension == "yaml" {
-		return New(YAML)
-	}
-
-	if fileExtension == "tf" || fileExtension == "tfvars" {
-		return New(HCL2)
-	}
-
-	if fileExtension == "gitignore" || fileExtension == "dockerignore" {
-		return New(IGNORE)
-	}
-
-	parser, err := New(fileExtension)
-	if err != nil {
-		return nil, fmt.Errorf("new: %w", err)
+	name, err := lookupFile(fileName, fileExtension)
+	switch {
+	case err == nil:
+		return name, nil
+	case detect:
+		return "", nil
+	case fileExtension == "":
+		return YAML, nil
+	default:
+		return "", err
 	}
-
-	return parser, nil
 }
 
 // Parsers returns a list of the supported Parsers.
 func Parsers() []string {
-	parsers := []string{
-		TOML,
-		HCL1,
-		HCL2,
-		CUE,
-		INI,
-		HOCON,
-		Dockerfile,
-		YAML,
-		JSON,
-		JSONNET,
-		EDN,
-		VCL,
-		XML,
-		IGNORE,
-	}
-
-	return parsers
+	return registeredNames()
 }
 
 // FileSupported returns true if the file at the given path is
@@ -163,11 +217,19 @@ func FileSupported(path string) bool {
 	return true
 }
 
+// FileRecognized returns true if the parser of the file at the given path
+// is determined by the name or extension of the file. Unlike FileSupported,
+// files without an extension are not assumed to be YAML.
+func FileRecognized(path string) bool {
+	name, err := parserFromPath(path, true)
+	return err == nil && name != ""
+}
+
 // ParseConfigurations parses and returns the configurations from the given
 // list of files. The result will be a map where the key is the file name of
 // the configuration.
 func ParseConfigurations(files []string) (map[string]interface{}, error) {
-	configurations, err := parseConfigurations(files, "")
+	configurations, err := parseConfigurations(files, Options{})
 	if err != nil {
 		return nil, err
 	}
@@ -179,7 +241,7 @@ func ParseConfigurations(files []string) (map[string]interface{}, error) {
 // configurations given in the file list. The result will be a map where the key
 // is the file name of the configuration.
 func ParseConfigurationsAs(files []string, parser string) (map[string]interface{}, error) {
-	configurations, err := parseConfigurations(files, parser)
+	configurations, err := parseConfigurations(files, Options{Parser: parser})
 	if err != nil {
 		return nil, err
 	}
@@ -187,6 +249,16 @@ func ParseConfigurationsAs(files []string, parser string) (map[string]interface{
 	return configurations, nil
 }
 
+// ParseConfigurationsWithOptions parses the files using the given options and
+// returns the configurations given in the file list. The result will be a map
+// where the key is the file name of the configuration.
+//
+// When ContinueOnError is set and some of the files fail to parse, the
+// configurations of the other files are returned along with ParseErrors.
+func ParseConfigurationsWithOptions(files []string, options Options) (map[string]interface{}, error) {
+	return parseConfigurations(files, options)
+}
+
 // CombineConfigurations takes the given configurations and combines them into a single
 // configuration. The result will be a map that contains a single key with a value of
 // Combined.
@@ -230,34 +302,129 @@ func CombineConfigurations(configs map[string]interface{}) map[string]interface{
 	return combinedConfigurations
 }
 
-func parseConfigurations(paths []string, parser string) (map[string]interface{}, error) {
+func parseConfigurations(paths []string, options Options) (map[string]interface{}, error) {
+	configurations, _, err := parseConfigurationsWithParsers(paths, options)
+	return configurations, err
+}
+
+// ParseConfigurationsWithParsers is like ParseConfigurationsWithOptions, but
+// also returns the name of the parser that was used for each configuration.
+// This is useful when the parsers are detected from the contents of the files.
+func ParseConfigurationsWithParsers(files []string, options Options) (map[string]interface{}, map[string]string, error) {
+	return parseConfigurationsWithParsers(files, options)
+}
+
+func parseConfigurationsWithParsers(paths []string, options Options) (map[string]interface{}, map[string]string, error) {
 	parsedConfigurations := make(map[string]interface{})
+	usedParsers := make(map[string]string)
+
+	var parseErrors ParseErrors
 	for _, path := range paths {
-		var fileParser Parser
+		parsed, name, err := parseConfiguration(path, options)
+		if err != nil && !options.ContinueOnError {
+			return nil, nil, err
+		}
+
+		if err != nil {
+			parseErrors = append(parseErrors, &FileError{Path: path, Err: err})
+			continue
+		}
+
+		parsedConfigurations[path] = parsed
+		usedParsers[path] = name
+	}
+
+	if len(parseErrors) > 0 {
+		return parsedConfigurations, usedParsers, parseErrors
+	}
+
+	return parsedConfigurations, usedParsers, nil
+}
+
+// parseConfiguration parses the configuration at the given path and
+// returns it together with the name of the parser that was used.
+func parseConfiguration(path string, options Options) (interface{}, string, error) {
+	name := options.Parser
+	if name == "" {
 		var err error
-		if parser == "" {
-			fileParser, err = NewFromPath(path)
-		} else {
-			fileParser, err = New(parser)
+		name, err = parserFromPath(path, options.DetectFormat)
+		if err != nil {
+			return nil, "", fmt.Errorf("new parser: %w", err)
 		}
+	}
+
+	// The contents are read up front when the format has to be detected,
+	// as standard input can only be read once.
+	var contents []byte
+	if name == "" {
+		var err error
+		contents, err = getConfigurationContent(path)
 		if err != nil {
-			return nil, fmt.Errorf("new parser: %w", err)
+			return nil, "", fmt.Errorf("get configuration content: %w", err)
 		}
 
-		contents, err := getConfigurationContent(path)
+		name, err = detectParser(path, contents)
 		if err != nil {
-			return nil, fmt.Errorf("get configuration content: %w", err)
+			return nil, "", fmt.Errorf("new parser: %w", err)
 		}
+	}
+
+	fileParser, err := New(name)
+	if err != nil {
+		return nil, "", fmt.Errorf("new parser: %w", err)
+	}
 
+	configure(fileParser, path, options)
+
+	if dirParser, ok := fileParser.(DirectoryParser); ok && isDirectory(path) {
 		var parsed interface{}
-		if err := fileParser.Unmarshal(contents, &parsed); err != nil {
-			return nil, fmt.Errorf("parser unmarshal: %w", err)
+		if err := dirParser.UnmarshalDirectory(path, &parsed); err != nil {
+			return nil, "", fmt.Errorf("parser unmarshal directory: %w", err)
 		}
 
-		parsedConfigurations[path] = parsed
+		return parsed, name, nil
+	}
+
+	if contents == nil {
+		contents, err = getConfigurationContent(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("get configuration content: %w", err)
+		}
+	}
+
+	var parsed interface{}
+	if err := fileParser.Unmarshal(contents, &parsed); err != nil {
+		return nil, "", fmt.Errorf("parser unmarshal: %w", err)
+	}
+
+	return parsed, name, nil
+}
+
+// configure applies the options that are specific to a parser.
+func configure(fileParser Parser, path string, options Options) {
+	if dockerParser, ok := fileParser.(*docker.Parser); ok {
+		dockerParser.Expand = options.ExpandArgs
+		dockerParser.BuildArgs = options.BuildArgs
+	}
+
+	if hcl2Parser, ok := fileParser.(*hcl2.Parser); ok {
+		hcl2Parser.Filename = path
+		hcl2Parser.Ranges = options.Ranges
+	}
+
+	if terraformParser, ok := fileParser.(*terraform.Parser); ok {
+		terraformParser.Filename = path
+		terraformParser.FollowModules = options.FollowModules
+	}
+}
+
+func isDirectory(path string) bool {
+	if path == "-" {
+		return false
 	}
 
-	return parsedConfigurations, nil
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
 }
 
 func getConfigurationContent(path string) ([]byte, error) {
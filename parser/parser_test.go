@@ -1,6 +1,9 @@
 package parser
 
 import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -105,3 +108,37 @@ func TestNewFromPath(t *testing.T) {
 		})
 	}
 }
+
+func TestParseConfigurationsWithOptions_ContinueOnError(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid.yaml")
+	if err := ioutil.WriteFile(valid, []byte("kind: Service\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := ioutil.WriteFile(invalid, []byte(`{"kind": `), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []string{invalid, valid}
+	if _, err := ParseConfigurationsWithOptions(files, Options{}); err == nil {
+		t.Fatal("expected an error without ContinueOnError")
+	}
+
+	configurations, err := ParseConfigurationsWithOptions(files, Options{ContinueOnError: true})
+
+	var parseErrors ParseErrors
+	if !errors.As(err, &parseErrors) {
+		t.Fatalf("expected parse errors, got: %v", err)
+	}
+
+	if len(parseErrors) != 1 || parseErrors[0].Path != invalid {
+		t.Errorf("expected a single error for %s, got: %v", invalid, parseErrors)
+	}
+
+	if _, ok := configurations[valid]; !ok || len(configurations) != 1 {
+		t.Errorf("expected only the configuration of %s, got: %v", valid, configurations)
+	}
+}
@@ -0,0 +1,143 @@
+package parser
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Factory creates a new instance of a Parser.
+type Factory func() Parser
+
+type registration struct {
+	name     string
+	factory  Factory
+	patterns []string
+}
+
+var (
+	registryMu    sync.RWMutex
+	registrations []*registration
+	byName        = make(map[string]*registration)
+	byExtension   = make(map[string]*registration)
+)
+
+// Register makes a parser available by the given name. The parser is used for
+// files that have any of the given extensions (without the leading dot).
+//
+// If Register is called twice with the same name or extension, or if the
+// factory is nil, it panics.
+func Register(name string, factory Factory, extensions ...string) {
+	if err := TryRegister(name, factory, extensions...); err != nil {
+		panic("parser: " + err.Error())
+	}
+}
+
+// TryRegister is like Register, but returns an error instead of panicking.
+// It is useful for parsers that are only known at runtime, such as the
+// parsers provided by plugins.
+func TryRegister(name string, factory Factory, extensions ...string) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		return fmt.Errorf("factory is nil for %s", name)
+	}
+
+	if _, exists := byName[name]; exists {
+		return fmt.Errorf("parser %s registered twice", name)
+	}
+
+	for _, extension := range extensions {
+		if r, exists := byExtension[strings.ToLower(extension)]; exists {
+			return fmt.Errorf("extension %s of parser %s already registered by parser %s", extension, name, r.name)
+		}
+	}
+
+	r := registration{
+		name:    name,
+		factory: factory,
+	}
+
+	registrations = append(registrations, &r)
+	byName[name] = &r
+	for _, extension := range extensions {
+		byExtension[strings.ToLower(extension)] = &r
+	}
+
+	return nil
+}
+
+// RegisterPatterns adds filename patterns to an already registered parser.
+// The parser is used for files whose name matches any of the patterns. Patterns
+// use the syntax of filepath.Match and are matched case-insensitively against the
+// name of the file (ex: Dockerfile.*). Patterns take precedence over extensions.
+//
+// If no parser is registered with the given name, or a pattern is invalid, it panics.
+func RegisterPatterns(name string, patterns ...string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	r, exists := byName[name]
+	if !exists {
+		panic("parser: register patterns called for unknown parser " + name)
+	}
+
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			panic("parser: invalid pattern " + pattern)
+		}
+
+		r.patterns = append(r.patterns, strings.ToLower(pattern))
+	}
+}
+
+// lookupName returns the factory of the parser with the given name.
+func lookupName(name string) (Factory, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	r, exists := byName[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown parser: %v", name)
+	}
+
+	return r.factory, nil
+}
+
+// lookupFile returns the name of the parser for the file with the given
+// name and extension. Filename patterns are checked before extensions.
+func lookupFile(fileName string, fileExtension string) (string, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	for _, r := range registrations {
+		for _, pattern := range r.patterns {
+			if matched, _ := filepath.Match(pattern, fileName); matched {
+				return r.name, nil
+			}
+		}
+	}
+
+	r, exists := byExtension[fileExtension]
+	if !exists {
+		return "", fmt.Errorf("unknown parser: %v", fileExtension)
+	}
+
+	return r.name, nil
+}
+
+// registeredNames returns the names of all of the registered
+// parsers in the order they were registered.
+func registeredNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	var names []string
+	for _, r := range registrations {
+		names = append(names, r.name)
+	}
+
+	return names
+}
@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+type customParser struct{}
+
+func (customParser) Unmarshal(p []byte, v interface{}) error {
+	return nil
+}
+
+// restoreRegistry restores the registry to its current state once the test
+// has finished, so that the parsers it registers do not leak into other tests,
+// or into the same test when it is run again with -count.
+func restoreRegistry(t *testing.T) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	savedRegistrations := append([]*registration(nil), registrations...)
+	savedPatterns := make(map[*registration][]string)
+	for _, r := range registrations {
+		savedPatterns[r] = append([]string(nil), r.patterns...)
+	}
+
+	savedByName := make(map[string]*registration)
+	for name, r := range byName {
+		savedByName[name] = r
+	}
+
+	savedByExtension := make(map[string]*registration)
+	for extension, r := range byExtension {
+		savedByExtension[extension] = r
+	}
+
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+
+		for r, patterns := range savedPatterns {
+			r.patterns = patterns
+		}
+
+		registrations = savedRegistrations
+		byName = savedByName
+		byExtension = savedByExtension
+	})
+}
+
+func TestRegister(t *testing.T) {
+	restoreRegistry(t)
+
+	Register("custom", func() Parser { return &customParser{} }, "custom", "cst")
+	RegisterPatterns("custom", "custom-*.conf")
+
+	expected := reflect.TypeOf(&customParser{})
+
+	byName, err := New("custom")
+	if err != nil {
+		t.Fatal("new:", err)
+	}
+
+	if reflect.TypeOf(byName) != expected {
+		t.Errorf("Unexpected parser by name. expected %v actual %v", expected, reflect.TypeOf(byName))
+	}
+
+	for _, path := range []string{"test.custom", "test.CST", "dir/Custom-app.conf"} {
+		byPath, err := NewFromPath(path)
+		if err != nil {
+			t.Fatalf("from path %s: %v", path, err)
+		}
+
+		if reflect.TypeOf(byPath) != expected {
+			t.Errorf("Unexpected parser for %s. expected %v actual %v", path, expected, reflect.TypeOf(byPath))
+		}
+	}
+
+	if _, err := NewFromPath("app.conf"); err == nil {
+		t.Error("expected an error for a file that does not match a pattern or extension")
+	}
+
+	var found bool
+	for _, name := range Parsers() {
+		if name == "custom" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("expected custom parser in the list of parsers: %v", Parsers())
+	}
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		extensions []string
+	}{
+		{name: YAML},
+		{name: "duplicate", extensions: []string{"yml"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			restoreRegistry(t)
+
+			defer func() {
+				if recover() == nil {
+					t.Error("expected register to panic")
+				}
+			}()
+
+			Register(testCase.name, func() Parser { return &customParser{} }, testCase.extensions...)
+		})
+	}
+}
@@ -0,0 +1,558 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/tmccombs/hcl2json/convert"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// Parser is a Terraform module parser.
+//
+// All of the .tf and .tf.json files of a module directory are merged into a
+// single document. Variables are set from their defaults, terraform.tfvars and
+// *.auto.tfvars, and references to variables and locals are evaluated where
+// possible. References that cannot be evaluated are left as-is.
+type Parser struct {
+
+	// Filename is the name of the file that is being parsed by Unmarshal. It
+	// is included in the errors, and files ending in .json are parsed as JSON.
+	// When empty, the file is parsed as main.tf.
+	Filename string
+
+	// FollowModules loads modules that have a local source (ex: ./modules/vpc)
+	// and adds the loaded module to the module block under the __module key.
+	FollowModules bool
+}
+
+// labelCount is the number of labels of the known top-level blocks.
+// Blocks with the same labels that are found in multiple files are
+// combined into a list, the same way duplicate blocks are within a file.
+var labelCount = map[string]int{
+	"resource": 2,
+	"data":     2,
+	"variable": 1,
+	"output":   1,
+	"module":   1,
+	"provider": 1,
+}
+
+// functions are a subset of the Terraform functions that can be
+// used when evaluating expressions.
+var functions = map[string]function.Function{
+	"coalesce":  stdlib.CoalesceFunc,
+	"concat":    stdlib.ConcatFunc,
+	"format":    stdlib.FormatFunc,
+	"join":      stdlib.JoinFunc,
+	"length":    stdlib.LengthFunc,
+	"lower":     stdlib.LowerFunc,
+	"merge":     stdlib.MergeFunc,
+	"trimspace": stdlib.TrimSpaceFunc,
+	"upper":     stdlib.UpperFunc,
+}
+
+// Unmarshal unmarshals a single Terraform file as if it
+// was the only file of a module.
+func (tp *Parser) Unmarshal(p []byte, v interface{}) error {
+	filename := tp.Filename
+	if filename == "" {
+		filename = "main.tf"
+	}
+
+	document, err := convertModuleFile(p, filename)
+	if err != nil {
+		return fmt.Errorf("convert file: %w", err)
+	}
+
+	module, err := tp.evaluate(document, nil, "", nil)
+	if err != nil {
+		return fmt.Errorf("evaluate: %w", err)
+	}
+
+	if err := remarshal(module, v); err != nil {
+		return fmt.Errorf("unmarshal terraform: %w", err)
+	}
+
+	return nil
+}
+
+// UnmarshalDirectory unmarshals all of the Terraform files in
+// the given directory into a single module document.
+func (tp *Parser) UnmarshalDirectory(dir string, v interface{}) error {
+	module, err := tp.loadModule(dir, nil, nil)
+	if err != nil {
+		return fmt.Errorf("load module: %w", err)
+	}
+
+	if err := remarshal(module, v); err != nil {
+		return fmt.Errorf("unmarshal terraform: %w", err)
+	}
+
+	return nil
+}
+
+// loadModule loads the module in the given directory. When inputs is not nil,
+// the module is a child module and its variables are set from the inputs
+// instead of from the tfvars files.
+func (tp *Parser) loadModule(dir string, inputs map[string]interface{}, parents []string) (map[string]interface{}, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("get abs: %w", err)
+	}
+
+	for _, parent := range parents {
+		if parent == absDir {
+			return nil, fmt.Errorf("module %s references itself", dir)
+		}
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read dir: %w", err)
+	}
+
+	document := make(map[string]interface{})
+	var varFiles []string
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(dir, file.Name())
+		switch {
+		case strings.HasSuffix(file.Name(), ".tf"), strings.HasSuffix(file.Name(), ".tf.json"):
+			contents, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("read file: %w", err)
+			}
+
+			fileDocument, err := convertModuleFile(contents, path)
+			if err != nil {
+				return nil, fmt.Errorf("convert file: %w", err)
+			}
+
+			if err := merge(document, fileDocument); err != nil {
+				return nil, fmt.Errorf("merge %s: %w", path, err)
+			}
+
+		case isVarFile(file.Name()):
+			varFiles = append(varFiles, path)
+		}
+	}
+
+	variables := inputs
+	if variables == nil {
+		variables, err = loadVarFiles(varFiles)
+		if err != nil {
+			return nil, fmt.Errorf("load var files: %w", err)
+		}
+	}
+
+	module, err := tp.evaluate(document, variables, dir, append(parents, absDir))
+	if err != nil {
+		return nil, fmt.Errorf("evaluate: %w", err)
+	}
+
+	return module, nil
+}
+
+// evaluate sets the values of the variables and locals of the module and
+// replaces all of the references to them in the document.
+func (tp *Parser) evaluate(document map[string]interface{}, variables map[string]interface{}, dir string, parents []string) (map[string]interface{}, error) {
+	vars := make(map[string]interface{})
+	if declared, ok := document["variable"].(map[string]interface{}); ok {
+		for name, variable := range declared {
+			if body, ok := variable.(map[string]interface{}); ok {
+				if def, ok := body["default"]; ok {
+					vars[name] = def
+				}
+			}
+		}
+	}
+
+	for name, value := range variables {
+		vars[name] = value
+	}
+
+	e := evaluator{
+		vars:   vars,
+		locals: make(map[string]interface{}),
+	}
+
+	// Locals can reference each other, so they are evaluated once for every
+	// local to make sure that chains of references are resolved.
+	locals, _ := document["locals"].(map[string]interface{})
+	for range locals {
+		resolved := make(map[string]interface{})
+		for name, value := range locals {
+			resolved[name] = e.resolve(value)
+		}
+
+		e.locals = resolved
+	}
+
+	module, _ := e.resolve(document).(map[string]interface{})
+	if !tp.FollowModules || dir == "" {
+		return module, nil
+	}
+
+	modules, _ := module["module"].(map[string]interface{})
+	for name, block := range modules {
+		body, ok := block.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		source, _ := body["source"].(string)
+		if !strings.HasPrefix(source, "./") && !strings.HasPrefix(source, "../") {
+			continue
+		}
+
+		inputs := make(map[string]interface{})
+		for key, value := range body {
+			switch key {
+			case "source", "version", "providers", "count", "for_each", "depends_on":
+				continue
+			}
+
+			inputs[key] = value
+		}
+
+		child, err := tp.loadModule(filepath.Join(dir, source), inputs, parents)
+		if err != nil {
+			return nil, fmt.Errorf("load module %s: %w", name, err)
+		}
+
+		body["__module"] = child
+	}
+
+	return module, nil
+}
+
+// evaluator evaluates expressions that reference variables and locals.
+type evaluator struct {
+	vars   map[string]interface{}
+	locals map[string]interface{}
+}
+
+// resolve replaces all of the expressions in the given value
+// that can be evaluated with the result of the evaluation.
+func (e *evaluator) resolve(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		resolved := make(map[string]interface{})
+		for key, item := range v {
+			resolved[key] = e.resolve(item)
+		}
+
+		return resolved
+
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved[i] = e.resolve(item)
+		}
+
+		return resolved
+
+	case string:
+		if !strings.Contains(v, "${") {
+			return v
+		}
+
+		result, ok := e.evaluate(v)
+		if !ok {
+			return v
+		}
+
+		return result
+	}
+
+	return value
+}
+
+// evaluate evaluates the given template. When the template references anything
+// other than variables and locals, or fails to evaluate, false is returned.
+func (e *evaluator) evaluate(template string) (interface{}, bool) {
+	expr, diags := hclsyntax.ParseTemplate([]byte(template), "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, false
+	}
+
+	for _, traversal := range expr.Variables() {
+		if root := traversal.RootName(); root != "var" && root != "local" {
+			return nil, false
+		}
+	}
+
+	vars, err := toValue(e.vars)
+	if err != nil {
+		return nil, false
+	}
+
+	locals, err := toValue(e.locals)
+	if err != nil {
+		return nil, false
+	}
+
+	ctx := hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var":   vars,
+			"local": locals,
+		},
+		Functions: functions,
+	}
+
+	value, diags := expr.Value(&ctx)
+	if diags.HasErrors() || !value.IsWhollyKnown() {
+		return nil, false
+	}
+
+	result, err := fromValue(value)
+	if err != nil {
+		return nil, false
+	}
+
+	return result, true
+}
+
+// merge merges the source document of a file into the destination document
+// of the module.
+func merge(dst map[string]interface{}, src map[string]interface{}) error {
+	for key, value := range src {
+		current, exists := dst[key]
+		if !exists {
+			dst[key] = value
+			continue
+		}
+
+		// All locals share the same namespace, so locals blocks
+		// are merged into a single block.
+		if key == "locals" {
+			currentLocals, ok := current.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("unexpected locals structure")
+			}
+
+			if err := mergeLocals(currentLocals, value); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		merged, err := mergeBlocks(current, value, labelCount[key])
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+
+		dst[key] = merged
+	}
+
+	return nil
+}
+
+// mergeLocals merges a locals block into the given locals. Locals may
+// only be defined once per module.
+func mergeLocals(dst map[string]interface{}, block interface{}) error {
+	locals, ok := block.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected locals structure")
+	}
+
+	for name, local := range locals {
+		if _, exists := dst[name]; exists {
+			return fmt.Errorf("duplicate local %q", name)
+		}
+
+		dst[name] = local
+	}
+
+	return nil
+}
+
+// mergeBlocks merges two blocks with the given number of labels. Once all of
+// the labels are merged, the bodies of the blocks are combined into a list.
+func mergeBlocks(dst interface{}, src interface{}, labels int) (interface{}, error) {
+	if labels == 0 {
+		return append(toList(dst), toList(src)...), nil
+	}
+
+	dstLabels, dstOk := dst.(map[string]interface{})
+	srcLabels, srcOk := src.(map[string]interface{})
+	if !dstOk || !srcOk {
+		return nil, fmt.Errorf("unexpected block structure")
+	}
+
+	for label, value := range srcLabels {
+		current, exists := dstLabels[label]
+		if !exists {
+			dstLabels[label] = value
+			continue
+		}
+
+		merged, err := mergeBlocks(current, value, labels-1)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", label, err)
+		}
+
+		dstLabels[label] = merged
+	}
+
+	return dstLabels, nil
+}
+
+func toList(value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		return list
+	}
+
+	return []interface{}{value}
+}
+
+// isVarFile returns true if the file is a variable definitions file
+// that Terraform loads automatically.
+func isVarFile(name string) bool {
+	return name == "terraform.tfvars" || name == "terraform.tfvars.json" ||
+		strings.HasSuffix(name, ".auto.tfvars") || strings.HasSuffix(name, ".auto.tfvars.json")
+}
+
+// loadVarFiles loads the variables from the given files. Same as Terraform,
+// terraform.tfvars files are loaded first, followed by the *.auto.tfvars files
+// in lexical order. Later files take precedence.
+func loadVarFiles(paths []string) (map[string]interface{}, error) {
+	sort.Slice(paths, func(i, j int) bool {
+		iAuto := strings.Contains(filepath.Base(paths[i]), ".auto.")
+		jAuto := strings.Contains(filepath.Base(paths[j]), ".auto.")
+		if iAuto != jAuto {
+			return jAuto
+		}
+
+		return paths[i] < paths[j]
+	})
+
+	variables := make(map[string]interface{})
+	for _, path := range paths {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+
+		values, err := convertFile(contents, path)
+		if err != nil {
+			return nil, fmt.Errorf("convert file: %w", err)
+		}
+
+		for name, value := range values {
+			variables[name] = value
+		}
+	}
+
+	return variables, nil
+}
+
+// convertModuleFile converts the contents of a .tf or .tf.json file into a
+// document. A file can have multiple locals blocks, which are converted into
+// a list, so they are flattened into a single block.
+func convertModuleFile(contents []byte, path string) (map[string]interface{}, error) {
+	document, err := convertFile(contents, path)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, ok := document["locals"].([]interface{})
+	if !ok {
+		return document, nil
+	}
+
+	locals := make(map[string]interface{})
+	for _, block := range blocks {
+		if err := mergeLocals(locals, block); err != nil {
+			return nil, err
+		}
+	}
+	document["locals"] = locals
+
+	return document, nil
+}
+
+// convertFile converts the contents of an HCL or JSON file into a document.
+func convertFile(contents []byte, path string) (map[string]interface{}, error) {
+	document := make(map[string]interface{})
+	if strings.HasSuffix(path, ".json") {
+		if err := json.Unmarshal(contents, &document); err != nil {
+			return nil, fmt.Errorf("unmarshal json: %w", err)
+		}
+
+		return document, nil
+	}
+
+	hclBytes, err := convert.Bytes(contents, path, convert.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("convert to bytes: %w", err)
+	}
+
+	if err := json.Unmarshal(hclBytes, &document); err != nil {
+		return nil, fmt.Errorf("unmarshal hcl2: %w", err)
+	}
+
+	return document, nil
+}
+
+// toValue converts a document into a cty value.
+func toValue(document map[string]interface{}) (cty.Value, error) {
+	if len(document) == 0 {
+		return cty.EmptyObjectVal, nil
+	}
+
+	contents, err := json.Marshal(document)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("marshal: %w", err)
+	}
+
+	valueType, err := ctyjson.ImpliedType(contents)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("implied type: %w", err)
+	}
+
+	value, err := ctyjson.Unmarshal(contents, valueType)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return value, nil
+}
+
+// fromValue converts a cty value into a document value.
+func fromValue(value cty.Value) (interface{}, error) {
+	contents, err := json.Marshal(ctyjson.SimpleJSONValue{Value: value})
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(contents, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return result, nil
+}
+
+func remarshal(document map[string]interface{}, v interface{}) error {
+	contents, err := json.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	return json.Unmarshal(contents, v)
+}
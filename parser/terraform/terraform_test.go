@@ -0,0 +1,185 @@
+package terraform
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParser_Unmarshal(t *testing.T) {
+	parser := Parser{}
+
+	sample := `variable "instance_type" {
+	default = "t2.micro"
+}
+
+locals {
+	prefix = "web"
+	name   = "${local.prefix}-${var.instance_type}"
+}
+
+resource "aws_instance" "web" {
+	instance_type = var.instance_type
+	subnet_id     = aws_subnet.main.id
+
+	tags = {
+		Name = local.name
+	}
+}`
+
+	var input map[string]interface{}
+	if err := parser.Unmarshal([]byte(sample), &input); err != nil {
+		t.Fatalf("parser should not have thrown an error: %v", err)
+	}
+
+	instance := input["resource"].(map[string]interface{})["aws_instance"].(map[string]interface{})["web"].(map[string]interface{})
+	if instance["instance_type"] != "t2.micro" {
+		t.Errorf("expected instance type to be resolved, got %v", instance["instance_type"])
+	}
+
+	if instance["subnet_id"] != "${aws_subnet.main.id}" {
+		t.Errorf("expected resource reference to be kept, got %v", instance["subnet_id"])
+	}
+
+	name := instance["tags"].(map[string]interface{})["Name"]
+	if name != "web-t2.micro" {
+		t.Errorf("expected chained locals to be resolved, got %v", name)
+	}
+}
+
+func TestParser_UnmarshalMultipleLocals(t *testing.T) {
+	parser := Parser{}
+
+	sample := `locals {
+	a = "x"
+}
+
+locals {
+	b = "${local.a}y"
+}
+
+resource "r" "n" {
+	a = local.a
+	b = local.b
+}`
+
+	var input map[string]interface{}
+	if err := parser.Unmarshal([]byte(sample), &input); err != nil {
+		t.Fatalf("parser should not have thrown an error: %v", err)
+	}
+
+	resource := input["resource"].(map[string]interface{})["r"].(map[string]interface{})["n"].(map[string]interface{})
+	if resource["a"] != "x" || resource["b"] != "xy" {
+		t.Errorf("expected the locals of all locals blocks to be resolved, got %v", resource)
+	}
+
+	duplicate := `locals {
+	a = "x"
+}
+
+locals {
+	a = "y"
+}`
+
+	err := parser.Unmarshal([]byte(duplicate), &input)
+	if err == nil || !strings.Contains(err.Error(), `duplicate local "a"`) {
+		t.Errorf("expected an error for the duplicate local, got: %v", err)
+	}
+}
+
+func TestParser_UnmarshalDirectoryMultipleLocals(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.tf":   "locals {\n  a = \"x\"\n}\n\nlocals {\n  b = \"y\"\n}\n\nresource \"r\" \"n\" {\n  v = \"${local.a}${local.b}${local.c}\"\n}\n",
+		"locals.tf": "locals {\n  c = \"z\"\n}\n",
+	}
+
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var input map[string]interface{}
+	if err := (&Parser{}).UnmarshalDirectory(dir, &input); err != nil {
+		t.Fatalf("parser should not have thrown an error: %v", err)
+	}
+
+	resource := input["resource"].(map[string]interface{})["r"].(map[string]interface{})["n"].(map[string]interface{})
+	if resource["v"] != "xyz" {
+		t.Errorf("expected the locals of all files to be resolved, got %v", resource["v"])
+	}
+
+	duplicate := "locals {\n  a = \"other\"\n}\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "duplicate.tf"), []byte(duplicate), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := (&Parser{}).UnmarshalDirectory(dir, &input)
+	if err == nil || !strings.Contains(err.Error(), `duplicate local "a"`) {
+		t.Errorf("expected an error for the duplicate local, got: %v", err)
+	}
+}
+
+func TestParser_UnmarshalFilename(t *testing.T) {
+	var input map[string]interface{}
+	err := (&Parser{Filename: "foo.tf"}).Unmarshal([]byte(`resource "r" "n" {`), &input)
+	if err == nil || !strings.Contains(err.Error(), "foo.tf") {
+		t.Errorf("expected the error to contain the name of the file, got: %v", err)
+	}
+
+	sample := `{"resource": {"r": {"n": {"v": "${var.v}"}}}, "variable": {"v": {"default": "x"}}}`
+	if err := (&Parser{Filename: "main.tf.json"}).Unmarshal([]byte(sample), &input); err != nil {
+		t.Fatalf("parser should not have thrown an error: %v", err)
+	}
+
+	resource := input["resource"].(map[string]interface{})["r"].(map[string]interface{})["n"].(map[string]interface{})
+	if resource["v"] != "x" {
+		t.Errorf("expected the variable to be resolved, got %v", resource["v"])
+	}
+}
+
+func TestParser_UnmarshalDirectory(t *testing.T) {
+	parser := Parser{FollowModules: true}
+
+	var input map[string]interface{}
+	if err := parser.UnmarshalDirectory("../../examples/terraform", &input); err != nil {
+		t.Fatalf("parser should not have thrown an error: %v", err)
+	}
+
+	instance := input["resource"].(map[string]interface{})["aws_instance"].(map[string]interface{})["web"].(map[string]interface{})
+	if instance["instance_type"] != "m5.24xlarge" {
+		t.Errorf("expected instance type from terraform.tfvars, got %v", instance["instance_type"])
+	}
+
+	worker := input["module"].(map[string]interface{})["worker"].(map[string]interface{})
+	module := worker["__module"].(map[string]interface{})
+	workerInstance := module["resource"].(map[string]interface{})["aws_instance"].(map[string]interface{})["worker"].(map[string]interface{})
+	if workerInstance["instance_type"] != "c5.large" {
+		t.Errorf("expected module input to be resolved, got %v", workerInstance["instance_type"])
+	}
+}
+
+func TestMergeBlocks(t *testing.T) {
+	dst := map[string]interface{}{
+		"provider": map[string]interface{}{
+			"aws": map[string]interface{}{"region": "us-east-1"},
+		},
+	}
+
+	src := map[string]interface{}{
+		"provider": map[string]interface{}{
+			"aws": map[string]interface{}{"region": "eu-west-1", "alias": "eu"},
+		},
+	}
+
+	if err := merge(dst, src); err != nil {
+		t.Fatalf("merge should not have thrown an error: %v", err)
+	}
+
+	providers := dst["provider"].(map[string]interface{})["aws"].([]interface{})
+	if len(providers) != 2 {
+		t.Errorf("expected duplicate provider blocks to be combined into a list, got %v", providers)
+	}
+}
@@ -0,0 +1,75 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Parser represents a parser provided by a plugin.
+//
+// When parsing, the contents of the file are written to the standard input
+// of the command, and the command is expected to write the parsed file as
+// JSON to its standard output.
+type Parser struct {
+	Name       string   `yaml:"name"`
+	Extensions []string `yaml:"extensions"`
+
+	// Command is the command that parses the file. When empty, the command
+	// of the plugin is used with the name of the parser as its argument.
+	Command string `yaml:"command"`
+
+	plugin *Plugin
+	ctx    context.Context
+}
+
+// WithContext returns a copy of the parser that executes its command with the
+// given context, so that parsing stops when the context is cancelled.
+func (p *Parser) WithContext(ctx context.Context) *Parser {
+	parser := *p
+	parser.ctx = ctx
+
+	return &parser
+}
+
+// Unmarshal unmarshals the file by executing the command of the parser.
+func (p *Parser) Unmarshal(contents []byte, v interface{}) error {
+	if p.plugin == nil {
+		return fmt.Errorf("parser %s is not loaded from a plugin", p.Name)
+	}
+
+	command := p.Command
+	var args []string
+	if command == "" {
+		command = p.plugin.Command
+		args = []string{p.Name}
+	}
+
+	ctx := p.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cmd, err := p.plugin.command(ctx, command, args)
+	if err != nil {
+		return fmt.Errorf("command: %w", err)
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(contents)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("plugin %s parser %s: %w: %s", p.plugin.Name, p.Name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	if err := json.Unmarshal(stdout.Bytes(), v); err != nil {
+		return fmt.Errorf("unmarshal plugin output: %w", err)
+	}
+
+	return nil
+}
@@ -21,11 +21,12 @@ const (
 
 // Plugin represents a plugin.
 type Plugin struct {
-	Name        string `yaml:"name"`
-	Version     string `yaml:"version"`
-	Usage       string `yaml:"usage"`
-	Description string `yaml:"description"`
-	Command     string `yaml:"command"`
+	Name        string   `yaml:"name"`
+	Version     string   `yaml:"version"`
+	Usage       string   `yaml:"usage"`
+	Description string   `yaml:"description"`
+	Command     string   `yaml:"command"`
+	Parsers     []Parser `yaml:"parsers"`
 }
 
 // Load loads a plugin given the name of the plugin.
@@ -84,29 +85,14 @@ func FindAll() ([]*Plugin, error) {
 // Arguments that are passed into Exec will be added after
 // any arguments that are defined in the plugins configuration.
 func (p *Plugin) Exec(ctx context.Context, args []string) error {
-
-	// Plugin configurations reference the CONFTEST_PLUGIN_DIR
-	// environment to be able to call the plugin.
-	os.Setenv("CONFTEST_PLUGIN_DIR", p.Directory())
-	expandedCommand := os.ExpandEnv(p.Command)
-
-	var command string
-	var arguments []string
-	var err error
-	if runtime.GOOS == "windows" {
-		command, arguments, err = parseWindowsCommand(expandedCommand, args)
-	} else {
-		command, arguments, err = parseCommand(expandedCommand, args)
-	}
+	cmd, err := p.command(ctx, p.Command, args)
 	if err != nil {
-		return fmt.Errorf("parse command: %w", err)
+		return fmt.Errorf("command: %w", err)
 	}
 
-	cmd := exec.CommandContext(ctx, command, arguments...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	cmd.Env = os.Environ()
 
 	// If an error is found during the execution of the plugin, figure
 	// out if the error was from not being able to execute the plugin or
@@ -134,6 +120,40 @@ func (p *Plugin) Exec(ctx context.Context, args []string) error {
 	return nil
 }
 
+// command returns the command to execute for the given plugin command
+// along with any arguments.
+func (p *Plugin) command(ctx context.Context, pluginCommand string, args []string) (*exec.Cmd, error) {
+
+	// Plugin configurations reference the CONFTEST_PLUGIN_DIR
+	// environment to be able to call the plugin. It is only set for
+	// the command, as parsers are executed in the middle of a run.
+	directory := p.Directory()
+	expandedCommand := os.Expand(pluginCommand, func(name string) string {
+		if name == "CONFTEST_PLUGIN_DIR" {
+			return directory
+		}
+
+		return os.Getenv(name)
+	})
+
+	var command string
+	var arguments []string
+	var err error
+	if runtime.GOOS == "windows" {
+		command, arguments, err = parseWindowsCommand(expandedCommand, args)
+	} else {
+		command, arguments, err = parseCommand(expandedCommand, args)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse command: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, command, arguments...)
+	cmd.Env = append(os.Environ(), "CONFTEST_PLUGIN_DIR="+directory)
+
+	return cmd, nil
+}
+
 // Directory returns the full path of the directory where the
 // plugin is stored in the plugin cache.
 func (p *Plugin) Directory() string {
@@ -165,6 +185,14 @@ func FromDirectory(directory string) (*Plugin, error) {
 		return nil, fmt.Errorf("unmarshal plugin: %w", err)
 	}
 
+	for i := range plugin.Parsers {
+		if plugin.Parsers[i].Name == "" {
+			return nil, fmt.Errorf("parser %d of plugin %s has no name", i, plugin.Name)
+		}
+
+		plugin.Parsers[i].plugin = &plugin
+	}
+
 	return &plugin, nil
 }
 
@@ -1,6 +1,11 @@
 package plugin
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 )
 
@@ -40,3 +45,83 @@ func TestParseCommand_WindowsShell(t *testing.T) {
 		t.Errorf("Unexpected argument. expected %v, actual %v", "arg2", arguments[2])
 	}
 }
+
+func TestParser_Unmarshal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cat is not available on windows")
+	}
+
+	directory := t.TempDir()
+	config := `name: "passthrough"
+command: "cat"
+parsers:
+- name: "passthrough"
+  extensions: ["pt"]
+  command: "cat"`
+
+	if err := ioutil.WriteFile(filepath.Join(directory, "plugin.yaml"), []byte(config), os.ModePerm); err != nil {
+		t.Fatal("write config:", err)
+	}
+
+	plugin, err := FromDirectory(directory)
+	if err != nil {
+		t.Fatal("from directory:", err)
+	}
+
+	if len(plugin.Parsers) != 1 || plugin.Parsers[0].Extensions[0] != "pt" {
+		t.Fatalf("Unexpected parsers. actual %v", plugin.Parsers)
+	}
+
+	var parsed map[string]interface{}
+	if err := plugin.Parsers[0].Unmarshal([]byte(`{"foo": "bar"}`), &parsed); err != nil {
+		t.Fatal("unmarshal:", err)
+	}
+
+	if parsed["foo"] != "bar" {
+		t.Errorf("Unexpected parsed output. expected %v, actual %v", "bar", parsed["foo"])
+	}
+}
+
+func TestParser_UnmarshalCancelled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cat is not available on windows")
+	}
+
+	plugin := &Plugin{Name: "passthrough", Command: "cat"}
+	parser := Parser{Name: "passthrough", Command: "cat", plugin: plugin}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var parsed map[string]interface{}
+	if err := parser.WithContext(ctx).Unmarshal([]byte(`{"foo": "bar"}`), &parsed); err == nil {
+		t.Error("expected an error when the context is cancelled")
+	}
+}
+
+func TestPlugin_CommandPluginDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the command is parsed differently on windows")
+	}
+
+	os.Unsetenv("CONFTEST_PLUGIN_DIR")
+
+	plugin := &Plugin{Name: "example"}
+	cmd, err := plugin.command(context.Background(), "$CONFTEST_PLUGIN_DIR/plugin.sh", nil)
+	if err != nil {
+		t.Fatal("command:", err)
+	}
+
+	expected := filepath.Join(plugin.Directory(), "plugin.sh")
+	if cmd.Path != expected {
+		t.Errorf("Unexpected command. expected %v, actual %v", expected, cmd.Path)
+	}
+
+	if cmd.Env[len(cmd.Env)-1] != "CONFTEST_PLUGIN_DIR="+plugin.Directory() {
+		t.Errorf("Expected CONFTEST_PLUGIN_DIR in the environment of the command, actual %v", cmd.Env)
+	}
+
+	if _, ok := os.LookupEnv("CONFTEST_PLUGIN_DIR"); ok {
+		t.Error("CONFTEST_PLUGIN_DIR must not be set in the environment of the process")
+	}
+}
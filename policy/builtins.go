@@ -0,0 +1,126 @@
+package policy
+
+import (
+	"fmt"
+
+	"github.com/open-policy-agent/conftest/parser"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/types"
+)
+
+// parseConfigBuiltin parses a string with any of the parsers of conftest, for
+// example a configuration file that is embedded in a Kubernetes ConfigMap:
+//
+//	config := conftest.parse_config("ini", input.data["settings.ini"])
+//
+// When the string cannot be parsed, the call is undefined.
+var parseConfigBuiltin = &rego.Function{
+	Name:    "conftest.parse_config",
+	Decl:    types.NewFunction(types.Args(types.S, types.S), types.A),
+	Memoize: true,
+}
+
+// builtins are the builtins that conftest adds to the builtins of OPA.
+var builtins = []*rego.Function{
+	parseConfigBuiltin,
+	fileBuiltin,
+}
+
+func init() {
+	rego.RegisterBuiltin2(parseConfigBuiltin, parseConfig)
+}
+
+// Builtin is a custom builtin function that the policies of an engine can
+// call, such as a lookup in a local catalog of services. Unlike the builtins
+// that are registered globally with rego.RegisterBuiltinDyn, it is only
+// available to the engine that it is loaded with.
+type Builtin struct {
+	Decl *rego.Function
+	Impl rego.BuiltinDyn
+}
+
+// validateBuiltins verifies that the custom builtins have a declaration and
+// an implementation, and that their names are not used by any other builtin.
+func validateBuiltins(builtins []Builtin) error {
+	names := make(map[string]bool)
+	for _, b := range builtins {
+		if b.Decl == nil || b.Decl.Name == "" || b.Decl.Decl == nil {
+			return fmt.Errorf("builtin must have a name and a declaration")
+		}
+
+		if b.Impl == nil {
+			return fmt.Errorf("builtin %s must have an implementation", b.Decl.Name)
+		}
+
+		if _, exists := ast.BuiltinMap[b.Decl.Name]; exists || names[b.Decl.Name] {
+			return fmt.Errorf("builtin %s already exists", b.Decl.Name)
+		}
+
+		names[b.Decl.Name] = true
+	}
+
+	return nil
+}
+
+// newRego returns a new instance of rego with the compiler, store, runtime,
+// custom builtins and coverage tracer of the engine, followed by the given
+// options.
+func (e *Engine) newRego(options ...func(r *rego.Rego)) *rego.Rego {
+	engineOptions := []func(r *rego.Rego){
+		rego.Compiler(e.Compiler()),
+		rego.Store(e.Store()),
+		rego.Runtime(e.Runtime()),
+	}
+
+	for _, b := range e.builtins {
+		engineOptions = append(engineOptions, rego.FunctionDyn(b.Decl, b.Impl))
+	}
+
+	if e.coverage != nil {
+		engineOptions = append(engineOptions, rego.QueryTracer(e.coverage))
+	}
+
+	return rego.New(append(engineOptions, options...)...)
+}
+
+func parseConfig(bctx rego.BuiltinContext, formatTerm *ast.Term, contentsTerm *ast.Term) (*ast.Term, error) {
+	format, ok := formatTerm.Value.(ast.String)
+	if !ok {
+		return nil, fmt.Errorf("format must be a string")
+	}
+
+	contents, ok := contentsTerm.Value.(ast.String)
+	if !ok {
+		return nil, fmt.Errorf("contents must be a string")
+	}
+
+	p, err := parser.New(string(format))
+	if err != nil {
+		return nil, fmt.Errorf("new parser: %w", err)
+	}
+
+	value, err := unmarshalValue(p, []byte(contents))
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", format, err)
+	}
+
+	return ast.NewTerm(value), nil
+}
+
+// unmarshalValue parses the contents with the parser and returns the
+// configuration as a Rego value.
+func unmarshalValue(p parser.Parser, contents []byte) (ast.Value, error) {
+	var config interface{}
+	if err := p.Unmarshal(contents, &config); err != nil {
+		return nil, err
+	}
+
+	value, err := ast.InterfaceToValue(config)
+	if err != nil {
+		return nil, fmt.Errorf("convert config: %w", err)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,175 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/types"
+)
+
+func TestParseConfig(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected interface{}
+	}{
+		{query: `conftest.parse_config("yaml", "level: debug\n")`, expected: map[string]interface{}{"level": "debug"}},
+		{query: `conftest.parse_config("ini", "[server]\nhost = localhost\n")`, expected: map[string]interface{}{"server": map[string]interface{}{"host": "localhost"}}},
+		{query: `conftest.parse_config("json", "{")`},
+		{query: `conftest.parse_config("unknown", "{}")`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.query, func(t *testing.T) {
+			resultSet, err := rego.New(rego.Query(testCase.query)).Eval(context.Background())
+			if err != nil {
+				t.Fatalf("eval: %v", err)
+			}
+
+			if testCase.expected == nil {
+				if len(resultSet) != 0 {
+					t.Errorf("expected the call to be undefined, got %v", resultSet)
+				}
+				return
+			}
+
+			if len(resultSet) != 1 {
+				t.Fatalf("expected a single result, got %v", resultSet)
+			}
+
+			actual := resultSet[0].Expressions[0].Value
+			if !reflect.DeepEqual(actual, testCase.expected) {
+				t.Errorf("expected %v, got %v", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+func TestWithBuiltins(t *testing.T) {
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"policy/catalog.rego": `package main
+
+deny[msg] {
+	not catalog.owner(input.service)
+	msg := sprintf("service %s is not in the catalog", [input.service])
+}
+
+test_known_service {
+	count(deny) == 0 with input as {"service": "payments"}
+}
+
+test_unknown_service {
+	deny["service billing is not in the catalog"] with input as {"service": "billing"}
+}
+`,
+	})
+	policies := []string{filepath.Join(dir, "policy")}
+
+	owners := map[string]string{"payments": "team-payments"}
+	catalogOwner := Builtin{
+		Decl: &rego.Function{
+			Name: "catalog.owner",
+			Decl: types.NewFunction(types.Args(types.S), types.S),
+		},
+		Impl: func(bctx rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+			service, ok := terms[0].Value.(ast.String)
+			if !ok {
+				return nil, fmt.Errorf("service must be a string")
+			}
+
+			owner, ok := owners[string(service)]
+			if !ok {
+				return nil, nil
+			}
+
+			return ast.StringTerm(owner), nil
+		},
+	}
+
+	if _, err := Load(ctx, policies); err == nil || !strings.Contains(err.Error(), "undefined function catalog.owner") {
+		t.Fatalf("expected the policies to not compile without the builtin, got: %v", err)
+	}
+
+	capabilities, err := LoadCapabilities("", true)
+	if err != nil {
+		t.Fatalf("load capabilities: %v", err)
+	}
+
+	engine, err := New(ctx, WithPolicies(policies...), WithCapabilities(capabilities), WithBuiltins(catalogOwner))
+	if err != nil {
+		t.Fatalf("loading policies: %v", err)
+	}
+
+	configs := map[string]interface{}{
+		"payments.yaml": map[string]interface{}{"service": "payments"},
+		"billing.yaml":  map[string]interface{}{"service": "billing"},
+	}
+
+	results, err := engine.Check(ctx, configs, "main")
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+
+	var failures []string
+	for _, result := range results {
+		for _, failure := range result.Failures {
+			failures = append(failures, failure.Message)
+		}
+	}
+
+	expected := []string{"service billing is not in the catalog"}
+	if !reflect.DeepEqual(failures, expected) {
+		t.Errorf("expected failures %v, got %v", expected, failures)
+	}
+
+	testResults, err := engine.RunTests(ctx)
+	if err != nil {
+		t.Fatalf("run tests: %v", err)
+	}
+
+	if len(testResults) != 2 {
+		t.Fatalf("expected 2 test results, got %v", len(testResults))
+	}
+
+	for _, result := range testResults {
+		if result.Fail || result.Error != nil {
+			t.Errorf("expected %s to pass, got: %v", result.Name, result.Error)
+		}
+	}
+}
+
+func TestWithBuiltins_Invalid(t *testing.T) {
+	impl := func(bctx rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+		return nil, nil
+	}
+	decl := types.NewFunction(types.Args(types.S), types.S)
+
+	testCases := []struct {
+		name     string
+		builtins []Builtin
+	}{
+		{name: "no declaration", builtins: []Builtin{{Impl: impl}}},
+		{name: "no implementation", builtins: []Builtin{{Decl: &rego.Function{Name: "custom.func", Decl: decl}}}},
+		{name: "builtin of opa", builtins: []Builtin{{Decl: &rego.Function{Name: "sprintf", Decl: decl}, Impl: impl}}},
+		{name: "duplicate", builtins: []Builtin{
+			{Decl: &rego.Function{Name: "custom.func", Decl: decl}, Impl: impl},
+			{Decl: &rego.Function{Name: "custom.func", Decl: decl}, Impl: impl},
+		}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := New(context.Background(), WithPolicies("../examples/kubernetes/policy"), WithBuiltins(testCase.builtins...)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// strictBuiltins are the builtins that are removed from the capabilities when
+// the builtins are restricted. They either access the network or return a
+// different result on each evaluation.
+var strictBuiltins = map[string]bool{
+	ast.HTTPSend.Name:         true,
+	ast.OPARuntime.Name:       true,
+	ast.NowNanos.Name:         true,
+	ast.UUIDRFC4122.Name:      true,
+	ast.JWTEncodeSign.Name:    true,
+	ast.JWTEncodeSignRaw.Name: true,
+}
+
+// LoadCapabilities returns the capabilities that the policies are compiled
+// with. The capabilities are read from the JSON file at the given path, in the
+// same format as OPA (see opa capabilities), or are those of the embedded
+// version of OPA when the path is empty. The builtins of conftest, such as
+// conftest.parse_config, are always included. When strict is set, the
+// builtins that access the network or that are nondeterministic, such as
+// http.send and time.now_ns, are removed from the capabilities.
+func LoadCapabilities(path string, strict bool) (*ast.Capabilities, error) {
+	capabilities := ast.CapabilitiesForThisVersion()
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("open capabilities: %w", err)
+		}
+		defer file.Close()
+
+		capabilities, err = ast.LoadCapabilitiesJSON(file)
+		if err != nil {
+			return nil, fmt.Errorf("load capabilities %s: %w", path, err)
+		}
+
+		// The builtins of conftest are not known to OPA, so they are
+		// always added to the capabilities.
+		known := make(map[string]bool)
+		for _, builtin := range capabilities.Builtins {
+			known[builtin.Name] = true
+		}
+
+		for _, builtin := range builtins {
+			if !known[builtin.Name] {
+				capabilities.Builtins = append(capabilities.Builtins, &ast.Builtin{Name: builtin.Name, Decl: builtin.Decl})
+			}
+		}
+	}
+
+	if strict {
+		var builtins []*ast.Builtin
+		for _, builtin := range capabilities.Builtins {
+			if !strictBuiltins[builtin.Name] {
+				builtins = append(builtins, builtin)
+			}
+		}
+
+		capabilities.Builtins = builtins
+	}
+
+	return capabilities, nil
+}
@@ -0,0 +1,69 @@
+package policy
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadCapabilities(t *testing.T) {
+	ctx := context.Background()
+	policies := []string{"../examples/capabilities/policy"}
+
+	capabilities, err := LoadCapabilities("", false)
+	if err != nil {
+		t.Fatalf("load capabilities: %v", err)
+	}
+
+	if _, err := New(ctx, WithPolicies(policies...), WithCapabilities(capabilities)); err != nil {
+		t.Errorf("expected the policies to compile with the default capabilities: %v", err)
+	}
+
+	testCases := []struct {
+		name   string
+		path   string
+		strict bool
+	}{
+		{name: "strict", strict: true},
+		{name: "capabilities file", path: "../examples/capabilities/capabilities.json"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			capabilities, err := LoadCapabilities(testCase.path, testCase.strict)
+			if err != nil {
+				t.Fatalf("load capabilities: %v", err)
+			}
+
+			_, err = New(ctx, WithPolicies(policies...), WithCapabilities(capabilities))
+			if err == nil || !strings.Contains(err.Error(), "undefined function time.now_ns") {
+				t.Errorf("expected time.now_ns to be undefined, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadCapabilities_MissingFile(t *testing.T) {
+	if _, err := LoadCapabilities(filepath.Join(t.TempDir(), "missing.json"), false); err == nil {
+		t.Error("expected an error for a missing capabilities file")
+	}
+}
+
+func TestLoadCapabilities_ConftestBuiltins(t *testing.T) {
+	capabilities, err := LoadCapabilities("../examples/capabilities/capabilities.json", true)
+	if err != nil {
+		t.Fatalf("load capabilities: %v", err)
+	}
+
+	var found bool
+	for _, builtin := range capabilities.Builtins {
+		if builtin.Name == parseConfigBuiltin.Name {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("expected the capabilities to include %s", parseConfigBuiltin.Name)
+	}
+}
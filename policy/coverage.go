@@ -0,0 +1,67 @@
+package policy
+
+import (
+	"math"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/cover"
+)
+
+// Coverage returns the lines of the policies that were evaluated by the
+// engine, and the percentage of the lines that were covered, both per policy
+// and overall. Unit tests, in files that end with _test.rego, are not part
+// of the report. The report is empty unless the engine was created with
+// WithCoverage.
+func (e *Engine) Coverage() cover.Report {
+	report := cover.Report{Files: make(map[string]*cover.FileReport)}
+	if e.coverage == nil {
+		return report
+	}
+
+	modules := make(map[string]*ast.Module)
+	for path, module := range e.Modules() {
+		if !strings.HasSuffix(path, "_test.rego") {
+			modules[path] = module
+		}
+	}
+
+	var covered, notCovered int
+	for path, fileReport := range e.coverage.Report(modules).Files {
+		if _, ok := modules[path]; !ok {
+			continue
+		}
+
+		fileCovered := countLines(fileReport.Covered)
+		fileNotCovered := countLines(fileReport.NotCovered)
+		fileReport.Coverage = percentage(fileCovered, fileCovered+fileNotCovered)
+		report.Files[path] = fileReport
+
+		covered += fileCovered
+		notCovered += fileNotCovered
+	}
+
+	report.Coverage = percentage(covered, covered+notCovered)
+	return report
+}
+
+// percentage returns the percentage of the lines that are covered, rounded
+// to two decimals. When there are no lines to cover, such as when there are
+// only unit tests, nothing is left uncovered, so the coverage is 100.
+func percentage(covered int, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+
+	return math.Round(10000*float64(covered)/float64(total)) / 100
+}
+
+// countLines returns the number of lines within the ranges.
+func countLines(ranges []cover.Range) int {
+	var lines int
+	for _, r := range ranges {
+		lines += r.End.Row - r.Start.Row + 1
+	}
+
+	return lines
+}
@@ -0,0 +1,78 @@
+package policy
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	ctx := context.Background()
+
+	engine, err := New(ctx, WithPolicies("../examples/kubernetes/policy"), WithCoverage())
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+
+	if _, err := engine.RunTests(ctx); err != nil {
+		t.Fatalf("run tests: %v", err)
+	}
+
+	report := engine.Coverage()
+	for path := range report.Files {
+		if strings.HasSuffix(path, "_test.rego") {
+			t.Errorf("expected unit tests to be excluded from the report, got %s", path)
+		}
+	}
+
+	deny, ok := report.Files["../examples/kubernetes/policy/deny.rego"]
+	if !ok {
+		t.Fatalf("expected deny.rego to be part of the report, got %v", report.Files)
+	}
+
+	if deny.Coverage != 91.67 {
+		t.Errorf("expected a coverage of 91.67 for deny.rego, got %v", deny.Coverage)
+	}
+
+	if !deny.IsNotCovered(19) {
+		t.Errorf("expected line 19 of deny.rego to not be covered")
+	}
+
+	if report.Coverage != 86.11 {
+		t.Errorf("expected an overall coverage of 86.11, got %v", report.Coverage)
+	}
+}
+
+func TestCoverage_Disabled(t *testing.T) {
+	engine, err := New(context.Background(), WithPolicies("../examples/kubernetes/policy"))
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+
+	if report := engine.Coverage(); len(report.Files) > 0 || report.Coverage != 0 {
+		t.Errorf("expected an empty report, got %+v", report)
+	}
+}
+
+func TestCoverage_NoLines(t *testing.T) {
+	ctx := context.Background()
+
+	tests := `package main
+
+test_nothing {
+	true
+}`
+
+	engine, err := New(ctx, WithModules(map[string]string{"policy/main_test.rego": tests}), WithCoverage())
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+
+	if _, err := engine.RunTests(ctx); err != nil {
+		t.Fatalf("run tests: %v", err)
+	}
+
+	if report := engine.Coverage(); report.Coverage != 100 {
+		t.Errorf("expected a coverage of 100 when there are no lines to cover, got %v", report.Coverage)
+	}
+}
@@ -0,0 +1,258 @@
+package policy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-policy-agent/conftest/parser"
+)
+
+// documentLoader loads data documents with the parsers of conftest and
+// merges them into a single data tree. A document that is an object is merged
+// into the tree at the prefix of the data path it was found in, if any.
+// Documents that are not objects, such as lists, are placed under the name
+// of the file without its extension.
+//
+// When nested is true, documents are instead placed at the path of their
+// directory relative to the data path, similar to OPA.
+type documentLoader struct {
+	data     map[string]interface{}
+	contents map[string]string
+	nested   bool
+
+	// owners are the files that set each value in the data tree,
+	// keyed by the path of the value (ex: services.ports).
+	owners map[string]string
+}
+
+func newDocumentLoader(nested bool) *documentLoader {
+	return &documentLoader{
+		data:     make(map[string]interface{}),
+		contents: make(map[string]string),
+		nested:   nested,
+		owners:   make(map[string]string),
+	}
+}
+
+// loadPath loads all of the documents at the given path under the keys of
+// the prefix. Directories are searched recursively for files that have a
+// known format. Hidden files and directories, such as .git, are skipped.
+func (l *documentLoader) loadPath(dataPath string, prefix []string) error {
+	info, err := os.Stat(dataPath)
+	if err != nil {
+		return fmt.Errorf("get file info: %w", err)
+	}
+
+	if !info.IsDir() {
+		return l.loadFile(dataPath, prefix)
+	}
+
+	walk := func(currentPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("walk path: %w", err)
+		}
+
+		if currentPath != dataPath && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if info.IsDir() || !parser.FileRecognized(currentPath) {
+			return nil
+		}
+
+		if !l.nested {
+			return l.loadFile(currentPath, prefix)
+		}
+
+		relDir, err := filepath.Rel(dataPath, filepath.Dir(currentPath))
+		if err != nil {
+			return fmt.Errorf("relative path: %w", err)
+		}
+
+		keys := append([]string{}, prefix...)
+		if relDir != "." {
+			keys = append(keys, strings.Split(filepath.ToSlash(relDir), "/")...)
+		}
+
+		return l.loadFile(currentPath, keys)
+	}
+
+	return filepath.Walk(dataPath, walk)
+}
+
+// loadFile parses the file and merges its document into the data tree
+// under the given keys.
+func (l *documentLoader) loadFile(filePath string, keys []string) error {
+	contents, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+
+	configurations, err := parser.ParseConfigurations([]string{filePath})
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", filePath, err)
+	}
+
+	displayPath := filepath.ToSlash(filepath.Clean(filePath))
+	l.contents[displayPath] = string(contents)
+
+	document := configurations[filePath]
+	object, ok := document.(map[string]interface{})
+	if !ok {
+		name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+		object = map[string]interface{}{name: document}
+	}
+
+	parent := l.data
+	for k, key := range keys {
+		child, exists := parent[key]
+		if !exists {
+			child = make(map[string]interface{})
+			parent[key] = child
+		}
+
+		childObject, ok := child.(map[string]interface{})
+		if !ok {
+			return l.conflict(keys[:k+1], displayPath)
+		}
+
+		parent = childObject
+	}
+
+	return l.merge(parent, object, keys, displayPath)
+}
+
+// merge merges the source object into the destination object. Objects are
+// merged recursively, while any other value may only be set by a single file.
+func (l *documentLoader) merge(dst map[string]interface{}, src map[string]interface{}, keys []string, filePath string) error {
+	for key, value := range src {
+		valueKeys := append(append([]string{}, keys...), key)
+
+		existing, exists := dst[key]
+		if !exists {
+			dst[key] = value
+			l.owners[strings.Join(valueKeys, ".")] = filePath
+			continue
+		}
+
+		existingObject, existingIsObject := existing.(map[string]interface{})
+		valueObject, valueIsObject := value.(map[string]interface{})
+		if !existingIsObject || !valueIsObject {
+			return l.conflict(valueKeys, filePath)
+		}
+
+		if err := l.merge(existingObject, valueObject, valueKeys, filePath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// conflict returns an error that names the file that is being loaded and
+// the file that previously set the value at the given keys.
+func (l *documentLoader) conflict(keys []string, filePath string) error {
+	var owner string
+	for k := len(keys); k > 0 && owner == ""; k-- {
+		owner = l.owners[strings.Join(keys[:k], ".")]
+	}
+
+	return fmt.Errorf("conflicting data at data.%s: set by both %s and %s", strings.Join(keys, "."), owner, filePath)
+}
+
+// loadDocuments loads the data documents at the given paths. It returns the
+// merged data tree, and the raw contents of each document keyed by its path.
+func loadDocuments(dataPaths []string, nested bool) (map[string]interface{}, map[string]string, error) {
+	loader := newDocumentLoader(nested)
+	for _, dataPath := range dataPaths {
+		path, prefix, err := splitDataPath(dataPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := loader.loadPath(path, prefix); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return loader.data, loader.contents, nil
+}
+
+// splitDataPath splits a data path of the form path:prefix into the path and
+// the keys of the prefix (ex: policy/exceptions:company.exceptions). Paths
+// that exist are never split, so that paths containing a colon, such as
+// Windows paths with a drive letter, can still be used without a prefix.
+func splitDataPath(dataPath string) (string, []string, error) {
+	separator := strings.LastIndex(dataPath, ":")
+	if separator < 0 {
+		return dataPath, nil, nil
+	}
+
+	if _, err := os.Stat(dataPath); err == nil {
+		return dataPath, nil, nil
+	}
+
+	path, prefix := dataPath[:separator], dataPath[separator+1:]
+	if strings.ContainsAny(prefix, `/\`) {
+		return dataPath, nil, nil
+	}
+
+	keys := strings.Split(prefix, ".")
+	for _, key := range keys {
+		if key == "" {
+			return "", nil, fmt.Errorf("invalid data prefix %q in %s", prefix, dataPath)
+		}
+	}
+
+	return path, keys, nil
+}
+
+// PrefixDataPaths roots the documents of each data path at the name of its
+// directory, unless the data path already sets a prefix. For example, both
+// policy/exceptions and policy/exceptions/my_data.yaml are loaded under
+// data.exceptions.
+func PrefixDataPaths(dataPaths []string) ([]string, error) {
+	var prefixedPaths []string
+	for _, dataPath := range dataPaths {
+		_, prefix, err := splitDataPath(dataPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if prefix != nil {
+			prefixedPaths = append(prefixedPaths, dataPath)
+			continue
+		}
+
+		info, err := os.Stat(dataPath)
+		if err != nil {
+			return nil, fmt.Errorf("get file info: %w", err)
+		}
+
+		dir, err := filepath.Abs(dataPath)
+		if err != nil {
+			return nil, fmt.Errorf("get abs: %w", err)
+		}
+
+		if !info.IsDir() {
+			dir = filepath.Dir(dir)
+		}
+
+		name := filepath.Base(dir)
+		if name == string(filepath.Separator) || name == "." || strings.HasSuffix(name, ":") {
+			prefixedPaths = append(prefixedPaths, dataPath)
+			continue
+		}
+
+		prefixedPaths = append(prefixedPaths, dataPath+":"+name)
+	}
+
+	return prefixedPaths, nil
+}
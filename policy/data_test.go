@@ -0,0 +1,230 @@
+package policy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadDocuments(t *testing.T) {
+	files := map[string]string{
+		"services.toml":         "[services]\nports = [22, 21]\n",
+		"regions/allowed.yaml":  "- eu-west-1\n- eu-central-1\n",
+		"regions/defaults.tf":   "default_region = \"eu-west-1\"\n",
+		"regions/.hidden.json":  `{"hidden": true}`,
+		"README":                "not data",
+		"images/registries.ini": "[docker]\nhost = docker.io\n",
+	}
+
+	testCases := []struct {
+		name     string
+		nested   bool
+		expected map[string]interface{}
+	}{
+		{
+			name: "merged",
+			expected: map[string]interface{}{
+				"services": map[string]interface{}{
+					"ports": []interface{}{int64(22), int64(21)},
+				},
+				"allowed":        []interface{}{"eu-west-1", "eu-central-1"},
+				"default_region": "eu-west-1",
+				"docker": map[string]interface{}{
+					"host": "docker.io",
+				},
+			},
+		},
+		{
+			name:   "nested",
+			nested: true,
+			expected: map[string]interface{}{
+				"services": map[string]interface{}{
+					"ports": []interface{}{int64(22), int64(21)},
+				},
+				"regions": map[string]interface{}{
+					"allowed":        []interface{}{"eu-west-1", "eu-central-1"},
+					"default_region": "eu-west-1",
+				},
+				"images": map[string]interface{}{
+					"docker": map[string]interface{}{
+						"host": "docker.io",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, files)
+
+			data, contents, err := loadDocuments([]string{dir}, tt.nested)
+			if err != nil {
+				t.Fatalf("load documents: %v", err)
+			}
+
+			if !reflect.DeepEqual(data, tt.expected) {
+				t.Errorf("expected data %v, got %v", tt.expected, data)
+			}
+
+			if len(contents) != 4 {
+				t.Errorf("expected the contents of 4 documents, got %v", len(contents))
+			}
+		})
+	}
+}
+
+// Documents in the subdirectories of a data path are merged at the root of
+// the data tree by default, as they were before nesting was supported.
+func TestLoadDocuments_Subdirectories(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"data/sub/x.json": `{"a": 1}`,
+	})
+
+	data, _, err := loadDocuments([]string{filepath.Join(dir, "data")}, false)
+	if err != nil {
+		t.Fatalf("load documents: %v", err)
+	}
+
+	expected := map[string]interface{}{"a": 1.0}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("expected data %v, got %v", expected, data)
+	}
+}
+
+func TestLoadDocuments_Conflict(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a/services.yaml": "services:\n  ports: [22]\n  names: [ssh]\n",
+		"b/services.json": `{"services": {"ports": [21]}}`,
+	})
+
+	_, _, err := loadDocuments([]string{filepath.Join(dir, "a"), filepath.Join(dir, "b")}, false)
+	if err == nil {
+		t.Fatal("expected an error for conflicting data")
+	}
+
+	for _, expected := range []string{"data.services.ports", "a/services.yaml", "b/services.json"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error to contain %q, got: %v", expected, err)
+		}
+	}
+}
+
+func TestLoadDocuments_Prefix(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"vendor/services.yaml":   "services:\n  ports: [22]\n",
+		"local/services.json":    `{"services": {"ports": [21]}}`,
+		"exceptions/allowed.yml": "- nginx\n",
+	})
+
+	dataPaths, err := PrefixDataPaths([]string{
+		filepath.Join(dir, "vendor") + ":vendor.defaults",
+		filepath.Join(dir, "local"),
+		filepath.Join(dir, "exceptions", "allowed.yml"),
+	})
+	if err != nil {
+		t.Fatalf("prefix data paths: %v", err)
+	}
+
+	data, _, err := loadDocuments(dataPaths, false)
+	if err != nil {
+		t.Fatalf("load documents: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"vendor": map[string]interface{}{
+			"defaults": map[string]interface{}{
+				"services": map[string]interface{}{"ports": []interface{}{22.0}},
+			},
+		},
+		"local": map[string]interface{}{
+			"services": map[string]interface{}{"ports": []interface{}{21.0}},
+		},
+		"exceptions": map[string]interface{}{
+			"allowed": []interface{}{"nginx"},
+		},
+	}
+
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("expected data %v, got %v", expected, data)
+	}
+}
+
+func TestLoadDocuments_PrefixConflict(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a/services.yaml": "ports: [22]\n",
+		"b/services.json": `{"ports": [21]}`,
+	})
+
+	_, _, err := loadDocuments([]string{filepath.Join(dir, "a") + ":services", filepath.Join(dir, "b") + ":services"}, false)
+	if err == nil {
+		t.Fatal("expected an error for conflicting data")
+	}
+
+	for _, expected := range []string{"data.services.ports", "a/services.yaml", "b/services.json"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error to contain %q, got: %v", expected, err)
+		}
+	}
+}
+
+func TestSplitDataPath(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "data:v1")
+	writeFiles(t, dir, map[string]string{"data:v1": "{}"})
+
+	testCases := []struct {
+		dataPath string
+		path     string
+		prefix   []string
+		err      bool
+	}{
+		{dataPath: "data", path: "data"},
+		{dataPath: "data:company.exceptions", path: "data", prefix: []string{"company", "exceptions"}},
+		{dataPath: `C:\data`, path: `C:\data`},
+		{dataPath: existing, path: existing},
+		{dataPath: "data:company..exceptions", err: true},
+		{dataPath: "data:", err: true},
+	}
+
+	for _, testCase := range testCases {
+		path, prefix, err := splitDataPath(testCase.dataPath)
+		if testCase.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", testCase.dataPath)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", testCase.dataPath, err)
+		}
+
+		if path != testCase.path || !reflect.DeepEqual(prefix, testCase.prefix) {
+			t.Errorf("%s: expected %s %v, got %s %v", testCase.dataPath, testCase.path, testCase.prefix, path, prefix)
+		}
+	}
+}
@@ -3,20 +3,24 @@ package policy
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/open-policy-agent/conftest/output"
 	"github.com/open-policy-agent/conftest/parser"
 
 	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/cover"
 	"github.com/open-policy-agent/opa/loader"
+	"github.com/open-policy-agent/opa/profiler"
 	"github.com/open-policy-agent/opa/rego"
 	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
 	"github.com/open-policy-agent/opa/version"
 )
 
@@ -28,93 +32,265 @@ type Engine struct {
 	store    storage.Store
 	policies map[string]string
 	docs     map[string]string
+
+	// parsers are the names of the parsers used for each file, and
+	// selectors are the selectors of each namespace, if any. Selectors
+	// are evaluated on first use, so they are guarded by selectorsMu.
+	parsers     map[string]string
+	selectorsMu sync.Mutex
+	selectors   map[string]*selector
+
+	// runtimeEnv are the patterns of the environment variables
+	// that are exposed to the policies through opa.runtime().
+	runtimeEnv []string
+
+	// roots are the directories that the policies can read files from
+	// with conftest.file, and files caches the values of those files.
+	roots []string
+	files *fileCache
+
+	// runtime is the value of opa.runtime(), when it is not built from
+	// the environment variables.
+	runtime *ast.Term
+
+	// queryTimeout is the time that the evaluation of a single query may
+	// take. When it is zero, the evaluation is only limited by the context.
+	queryTimeout time.Duration
+
+	// profiling records the profile of the evaluation of the policies,
+	// when profiling is enabled.
+	profiling *profileRecorder
+
+	// coverage records the lines of the policies that are evaluated,
+	// when coverage is enabled.
+	coverage *cover.Cover
+
+	// capabilities are the capabilities that the policies are compiled
+	// with, and builtins are the custom builtins of the engine.
+	capabilities *ast.Capabilities
+	builtins     []Builtin
 }
 
-// Load returns an Engine after loading all of the specified policies.
-func Load(ctx context.Context, policyPaths []string) (*Engine, error) {
-	policies, err := loader.AllRegos(policyPaths)
-	if err != nil {
-		return nil, fmt.Errorf("load: %w", err)
-	} else if len(policies.Modules) == 0 {
-		return nil, fmt.Errorf("no policies found in %v", policyPaths)
+// New returns an Engine that is configured with the given options. At least
+// one policy must be loaded, either from disk with WithPolicies or from
+// memory with WithModules.
+func New(ctx context.Context, opts ...Option) (*Engine, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
 	}
 
-	compiler, err := policies.Compiler()
+	if err := validateBuiltins(o.builtins); err != nil {
+		return nil, err
+	}
+
+	modules, policyContents, err := loadModules(o.policyPaths, o.modules)
 	if err != nil {
-		return nil, fmt.Errorf("get compiler: %w", err)
+		return nil, fmt.Errorf("loading policies: %w", err)
 	}
 
-	policyContents := make(map[string]string)
-	for path, module := range policies.ParsedModules() {
-		path = filepath.Clean(path)
-		path = filepath.ToSlash(path)
+	engine := Engine{
+		trace:        o.trace,
+		modules:      modules,
+		capabilities: o.capabilities,
+		builtins:     o.builtins,
+		runtime:      o.runtime,
+		queryTimeout: o.queryTimeout,
+		policies:     policyContents,
+		files:        &fileCache{values: make(map[string]fileCacheEntry)},
+	}
 
-		policyContents[path] = module.String()
+	if err := engine.compile(); err != nil {
+		return nil, fmt.Errorf("loading policies: %w", err)
 	}
 
-	engine := Engine{
-		modules:  policies.ParsedModules(),
-		compiler: compiler,
-		policies: policyContents,
+	if o.profile {
+		engine.profiling = newProfileRecorder()
+	}
+
+	if o.coverage {
+		engine.coverage = cover.New()
+	}
+
+	// Data documents are loaded with the same parsers as the configurations,
+	// so any file that can be tested can also be used as data.
+	data, documentContents, err := loadDocuments(o.dataPaths, o.nestedData)
+	if err != nil {
+		return nil, fmt.Errorf("load documents: %w", err)
+	}
+	engine.docs = documentContents
+
+	engine.store = o.store
+	if engine.store == nil {
+		engine.store = inmem.NewFromObject(data)
+	} else if err := writeDocuments(ctx, engine.store, data); err != nil {
+		return nil, fmt.Errorf("write documents: %w", err)
 	}
 
 	return &engine, nil
 }
 
+// Load returns an Engine after loading all of the specified policies.
+func Load(ctx context.Context, policyPaths []string) (*Engine, error) {
+	return New(ctx, WithPolicies(policyPaths...))
+}
+
 // LoadWithData returns an Engine after loading all of the specified policies and data paths.
+// The data paths are recursively searched for files in any of the supported formats.
 func LoadWithData(ctx context.Context, policyPaths []string, dataPaths []string) (*Engine, error) {
-	engine, err := Load(ctx, policyPaths)
-	if err != nil {
-		return nil, fmt.Errorf("loading policies: %w", err)
+	return New(ctx, WithPolicies(policyPaths...), WithData(dataPaths...))
+}
+
+// loadModules loads the policies at the given paths and parses the policies
+// from memory. It returns the parsed modules and the contents of each policy,
+// both keyed by the path or name of the policy.
+func loadModules(policyPaths []string, sources map[string]string) (map[string]*ast.Module, map[string]string, error) {
+	modules := make(map[string]*ast.Module)
+	if len(policyPaths) > 0 {
+		policies, err := loader.AllRegos(policyPaths)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load: %w", err)
+		}
+
+		for path, module := range policies.ParsedModules() {
+			modules[path] = module
+		}
 	}
 
-	// FilteredPaths will recursively find all file paths that contain a valid document
-	// extension from the given list of data paths.
-	allDocumentPaths, err := loader.FilteredPaths(dataPaths, func(abspath string, info os.FileInfo, depth int) bool {
-		if info.IsDir() {
-			return false
+	for name, source := range sources {
+		module, err := ast.ParseModule(name, source)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse module: %w", err)
 		}
-		return !contains([]string{".yaml", ".yml", ".json"}, filepath.Ext(info.Name()))
+
+		if module == nil {
+			return nil, nil, fmt.Errorf("parse module %s: empty module", name)
+		}
+
+		modules[name] = module
+	}
+
+	if len(modules) == 0 {
+		return nil, nil, fmt.Errorf("no policies found in %v", policyPaths)
+	}
+
+	policyContents := make(map[string]string)
+	for path, module := range modules {
+		path = filepath.Clean(path)
+		path = filepath.ToSlash(path)
+
+		policyContents[path] = module.String()
+	}
+
+	return modules, policyContents, nil
+}
+
+// compile compiles the policies with the capabilities of the engine, to
+// which the declarations of the custom builtins are added.
+func (e *Engine) compile() error {
+	capabilities := e.capabilities
+	if capabilities == nil {
+		capabilities = ast.CapabilitiesForThisVersion()
+	}
+
+	if len(e.builtins) > 0 {
+		capabilities = &ast.Capabilities{
+			Builtins:        append([]*ast.Builtin{}, capabilities.Builtins...),
+			WasmABIVersions: capabilities.WasmABIVersions,
+		}
+
+		for _, b := range e.builtins {
+			capabilities.Builtins = append(capabilities.Builtins, &ast.Builtin{Name: b.Decl.Name, Decl: b.Decl.Decl})
+		}
+	}
+
+	// Similar to opa test, tests with the same name are renamed so that
+	// each of them is run on its own.
+	compiler := ast.NewCompiler().WithCapabilities(capabilities).WithStageAfter("ResolveRefs", ast.CompilerStageDefinition{
+		Name:       "RewriteDuplicateTestNames",
+		MetricName: "rewrite_duplicate_test_names",
+		Stage:      rewriteDuplicateTestNames,
 	})
-	if err != nil {
-		return nil, fmt.Errorf("filter data paths: %w", err)
+
+	compiler.Compile(e.modules)
+	if compiler.Failed() {
+		return fmt.Errorf("get compiler: %w", compiler.Errors)
 	}
 
-	documents, err := loader.NewFileLoader().All(allDocumentPaths)
-	if err != nil {
-		return nil, fmt.Errorf("load documents: %w", err)
+	e.compiler = compiler
+	return nil
+}
+
+// writeDocuments writes each of the top-level documents of the data to the
+// store, replacing any document that already exists at the same path.
+func writeDocuments(ctx context.Context, store storage.Store, data map[string]interface{}) error {
+	if len(data) == 0 {
+		return nil
 	}
-	store, err := documents.Store()
+
+	txn, err := store.NewTransaction(ctx, storage.WriteParams)
 	if err != nil {
-		return nil, fmt.Errorf("get documents store: %w", err)
+		return fmt.Errorf("new transaction: %w", err)
 	}
 
-	documentContents := make(map[string]string)
-	for _, documentPath := range allDocumentPaths {
-		contents, err := ioutil.ReadFile(documentPath)
-		if err != nil {
-			return nil, fmt.Errorf("read file: %w", err)
+	for key, value := range data {
+		if err := store.Write(ctx, txn, storage.AddOp, storage.Path{key}, value); err != nil {
+			store.Abort(ctx, txn)
+			return fmt.Errorf("write %s: %w", key, err)
 		}
-
-		documentPath = filepath.Clean(documentPath)
-		documentPath = filepath.ToSlash(documentPath)
-		documentContents[documentPath] = string(contents)
 	}
 
-	engine.store = store
-	engine.docs = documentContents
+	if err := store.Commit(ctx, txn); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
 
-	return engine, nil
+	return nil
 }
 
 func (e *Engine) EnableTracing() {
 	e.trace = true
 }
 
+// SetParsers sets the names of the parsers that were used for the files,
+// keyed by the path of each file. They are used to select the namespaces
+// that apply to a file. When the parser of a file is not set, it is
+// determined by the path of the file.
+func (e *Engine) SetParsers(parsers map[string]string) {
+	e.parsers = parsers
+}
+
+// SetParams exposes the parameters to the policies under data.conftest.params.
+func (e *Engine) SetParams(ctx context.Context, params map[string]interface{}) error {
+	txn, err := e.store.NewTransaction(ctx, storage.WriteParams)
+	if err != nil {
+		return fmt.Errorf("new transaction: %w", err)
+	}
+
+	if err := writeConftestDocument(ctx, e.store, txn, "params", params); err != nil {
+		e.store.Abort(ctx, txn)
+		return fmt.Errorf("write params: %w", err)
+	}
+
+	if err := e.store.Commit(ctx, txn); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
+
 // Check executes all of the loaded policies against the input and returns the results.
+// Files that are not selected by the __conftest__ rule of the namespace are skipped.
 func (e *Engine) Check(ctx context.Context, configs map[string]interface{}, namespace string) ([]output.CheckResult, error) {
 	var checkResults []output.CheckResult
 	for path, config := range configs {
+		applies, err := e.applies(ctx, namespace, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !applies {
+			continue
+		}
 
 		// It is possible for a configuration to have multiple configurations. An example of this
 		// are multi-document yaml files where a single filepath represents multiple configs.
@@ -127,8 +303,8 @@ func (e *Engine) Check(ctx context.Context, configs map[string]interface{}, name
 				FileName:  path,
 				Namespace: namespace,
 			}
-			for _, subconfig := range subconfigs {
-				result, err := e.check(ctx, path, subconfig, namespace)
+			for index, subconfig := range subconfigs {
+				result, err := e.check(ctx, path, subconfig, namespace, e.newFileMetadata(path, index))
 				if err != nil {
 					return nil, fmt.Errorf("check: %w", err)
 				}
@@ -137,13 +313,14 @@ func (e *Engine) Check(ctx context.Context, configs map[string]interface{}, name
 				checkResult.Failures = append(checkResult.Failures, result.Failures...)
 				checkResult.Warnings = append(checkResult.Warnings, result.Warnings...)
 				checkResult.Exceptions = append(checkResult.Exceptions, result.Exceptions...)
+				checkResult.Errors = append(checkResult.Errors, result.Errors...)
 				checkResult.Queries = append(checkResult.Queries, result.Queries...)
 			}
 			checkResults = append(checkResults, checkResult)
 			continue
 		}
 
-		checkResult, err := e.check(ctx, path, config, namespace)
+		checkResult, err := e.check(ctx, path, config, namespace, e.newFileMetadata(path, 0))
 		if err != nil {
 			return nil, fmt.Errorf("check: %w", err)
 		}
@@ -155,10 +332,27 @@ func (e *Engine) Check(ctx context.Context, configs map[string]interface{}, name
 }
 
 // CheckCombined combines the input and evaluates the policies against the combined result.
+// Only the files that are selected by the __conftest__ rule of the namespace are combined.
 func (e *Engine) CheckCombined(ctx context.Context, configs map[string]interface{}, namespace string) (output.CheckResult, error) {
-	combinedConfigs := parser.CombineConfigurations(configs)
+	selectedConfigs := make(map[string]interface{})
+	for path, config := range configs {
+		applies, err := e.applies(ctx, namespace, path)
+		if err != nil {
+			return output.CheckResult{}, err
+		}
+
+		if applies {
+			selectedConfigs[path] = config
+		}
+	}
+
+	if len(selectedConfigs) == 0 && len(configs) > 0 {
+		return output.CheckResult{FileName: "Combined", Namespace: namespace}, nil
+	}
+
+	combinedConfigs := parser.CombineConfigurations(selectedConfigs)
 
-	result, err := e.check(ctx, "Combined", combinedConfigs["Combined"], namespace)
+	result, err := e.check(ctx, "Combined", combinedConfigs["Combined"], namespace, nil)
 	if err != nil {
 		return output.CheckResult{}, fmt.Errorf("check: %w", err)
 	}
@@ -210,29 +404,28 @@ func (e *Engine) Modules() map[string]*ast.Module {
 	return e.modules
 }
 
-// Runtime returns the runtime of the engine.
+// Runtime returns the runtime of the engine, unless it is set with WithRuntime.
+// Only the environment variables that match the patterns set with SetRuntimeEnv
+// are included.
 func (e *Engine) Runtime() *ast.Term {
-	env := ast.NewObject()
-	for _, pair := range os.Environ() {
-		parts := strings.SplitN(pair, "=", 2)
-		if len(parts) == 1 {
-			env.Insert(ast.StringTerm(parts[0]), ast.NullTerm())
-		} else if len(parts) > 1 {
-			env.Insert(ast.StringTerm(parts[0]), ast.StringTerm(parts[1]))
-		}
+	if e.runtime != nil {
+		return e.runtime
 	}
 
 	obj := ast.NewObject()
-	obj.Insert(ast.StringTerm("env"), ast.NewTerm(env))
+	obj.Insert(ast.StringTerm("env"), ast.NewTerm(e.runtimeEnvObject()))
 	obj.Insert(ast.StringTerm("version"), ast.StringTerm(version.Version))
 	obj.Insert(ast.StringTerm("commit"), ast.StringTerm(version.Vcs))
 
 	return ast.NewTerm(obj)
 }
 
-func (e *Engine) check(ctx context.Context, path string, config interface{}, namespace string) (output.CheckResult, error) {
+// check evaluates the policies of the namespace against the config. The metadata
+// of the file is exposed to the policies under data.conftest.file, unless it is nil.
+func (e *Engine) check(ctx context.Context, path string, config interface{}, namespace string, file *fileMetadata) (output.CheckResult, error) {
 	var rules []string
 	var ruleCount int
+	ruleBodies := make(map[string]int)
 	for _, module := range e.Modules() {
 		currentNamespace := strings.Replace(module.Package.Path.String(), "data.", "", 1)
 		if currentNamespace != namespace {
@@ -255,6 +448,7 @@ func (e *Engine) check(ctx context.Context, path string, config interface{}, nam
 			// For example, a policy can have two deny rules that both contain different bodies. In this case the list
 			// of rules will only contain deny, but the rule count would be two.
 			ruleCount++
+			ruleBodies[currentRule]++
 
 			if !contains(rules, currentRule) {
 				rules = append(rules, currentRule)
@@ -267,14 +461,24 @@ func (e *Engine) check(ctx context.Context, path string, config interface{}, nam
 		Namespace: namespace,
 	}
 	var successes int
+	var timeoutErr *queryTimeoutError
 	for _, rule := range rules {
 
 		// When matching rules for exceptions, only the name of the rule
 		// is queried, so the severity prefix must be removed.
 		exceptionQuery := fmt.Sprintf("data.%s.exception[_][_] == %q", namespace, removeRulePrefix(rule))
 
-		exceptionQueryResult, err := e.query(ctx, config, exceptionQuery)
-		if err != nil {
+		// A query that times out is reported as an error of the file, and the
+		// remaining rules are still evaluated. The rule is neither a success
+		// nor a failure.
+		start := time.Now()
+		exceptionQueryResult, err := e.query(ctx, config, exceptionQuery, file)
+		e.profiling.recordEvaluation(namespace, rule, path, time.Since(start))
+		if errors.As(err, &timeoutErr) {
+			checkResult.Errors = append(checkResult.Errors, output.Result{Message: timeoutErr.message(path)})
+			ruleCount -= ruleBodies[rule]
+			continue
+		} else if err != nil {
 			return output.CheckResult{}, fmt.Errorf("query exception: %w", err)
 		}
 
@@ -291,8 +495,14 @@ func (e *Engine) check(ctx context.Context, path string, config interface{}, nam
 		}
 
 		ruleQuery := fmt.Sprintf("data.%s.%s", namespace, rule)
-		ruleQueryResult, err := e.query(ctx, config, ruleQuery)
-		if err != nil {
+		start = time.Now()
+		ruleQueryResult, err := e.query(ctx, config, ruleQuery, file)
+		e.profiling.recordEvaluation(namespace, rule, path, time.Since(start))
+		if errors.As(err, &timeoutErr) {
+			checkResult.Errors = append(checkResult.Errors, output.Result{Message: timeoutErr.message(path)})
+			ruleCount -= ruleBodies[rule]
+			continue
+		} else if err != nil {
 			return output.CheckResult{}, fmt.Errorf("query rule: %w", err)
 		}
 
@@ -345,19 +555,44 @@ func (e *Engine) check(ctx context.Context, path string, config interface{}, nam
 // Example queries could include:
 // data.main.deny to query the deny rule in the main namespace
 // data.main.warn to query the warn rule in the main namespace
-func (e *Engine) query(ctx context.Context, input interface{}, query string) (output.QueryResult, error) {
+func (e *Engine) query(ctx context.Context, input interface{}, query string, file *fileMetadata) (output.QueryResult, error) {
 	options := []func(r *rego.Rego){
 		rego.Input(input),
 		rego.Query(query),
-		rego.Compiler(e.Compiler()),
-		rego.Store(e.Store()),
-		rego.Runtime(e.Runtime()),
 		rego.Trace(e.trace),
 	}
 
-	regoInstance := rego.New(options...)
-	resultSet, err := regoInstance.Eval(ctx)
-	if err != nil {
+	// The metadata of the file is read from a view of the store, so that
+	// it never ends up in the store itself.
+	if file != nil {
+		options = append(options, rego.Store(newFileStore(e.Store(), file)))
+
+		var err error
+		ctx, err = e.withFileContext(ctx, file.path)
+		if err != nil {
+			return output.QueryResult{}, fmt.Errorf("file context: %w", err)
+		}
+	}
+
+	evalCtx := ctx
+	if e.queryTimeout > 0 {
+		var cancel context.CancelFunc
+		evalCtx, cancel = context.WithTimeout(ctx, e.queryTimeout)
+		defer cancel()
+	}
+
+	var queryProfiler *profiler.Profiler
+	if e.profiling != nil {
+		queryProfiler = profiler.New()
+		options = append(options, rego.QueryTracer(queryProfiler))
+	}
+
+	regoInstance := e.newRego(options...)
+	resultSet, err := regoInstance.Eval(evalCtx)
+	e.profiling.recordExpressions(queryProfiler)
+	if err != nil && ctx.Err() == nil && errors.Is(evalCtx.Err(), context.DeadlineExceeded) {
+		return output.QueryResult{}, &queryTimeoutError{query: query, timeout: e.queryTimeout}
+	} else if err != nil {
 		return output.QueryResult{}, fmt.Errorf("evaluating policy: %w", err)
 	}
 
@@ -423,6 +658,22 @@ func (e *Engine) query(ctx context.Context, input interface{}, query string) (ou
 	return queryResult, nil
 }
 
+// queryTimeoutError is returned by query when the evaluation of the
+// query takes longer than the query timeout of the engine.
+type queryTimeoutError struct {
+	query   string
+	timeout time.Duration
+}
+
+func (e *queryTimeoutError) Error() string {
+	return fmt.Sprintf("query %s timed out after %s", e.query, e.timeout)
+}
+
+// message returns the message of the error that is reported for the file.
+func (e *queryTimeoutError) message(path string) string {
+	return fmt.Sprintf("evaluating %s against %s timed out after %s", e.query, path, e.timeout)
+}
+
 func isWarning(rule string) bool {
 	warningRegex := regexp.MustCompile("^warn(_[a-zA-Z0-9]+)*$")
 	return warningRegex.MatchString(rule)
@@ -2,9 +2,14 @@ package policy
 
 import (
 	"context"
+	"reflect"
+	"sort"
 	"testing"
+	"time"
 
+	"github.com/open-policy-agent/conftest/output"
 	"github.com/open-policy-agent/conftest/parser"
+	"github.com/open-policy-agent/opa/storage"
 )
 
 func TestException(t *testing.T) {
@@ -245,3 +250,214 @@ func TestIsFailure(t *testing.T) {
 		})
 	}
 }
+
+func TestSelector(t *testing.T) {
+	ctx := context.Background()
+
+	engine, err := Load(ctx, []string{"../examples/selector/policy"})
+	if err != nil {
+		t.Fatalf("loading policies: %v", err)
+	}
+
+	parsed, err := parser.ParseConfigurations([]string{"../examples/selector/k8s/service.yaml", "../examples/selector/Dockerfile"})
+	if err != nil {
+		t.Fatalf("loading configs: %v", err)
+	}
+
+	// The file patterns of the selectors are relative to the working directory.
+	configs := map[string]interface{}{
+		"k8s/service.yaml": parsed["../examples/selector/k8s/service.yaml"],
+		"Dockerfile":       parsed["../examples/selector/Dockerfile"],
+		"other/svc.yaml":   parsed["../examples/selector/k8s/service.yaml"],
+	}
+
+	engine.SetParsers(map[string]string{
+		"k8s/service.yaml": parser.YAML,
+		"Dockerfile":       parser.Dockerfile,
+		"other/svc.yaml":   parser.YAML,
+	})
+
+	testCases := []struct {
+		namespace string
+		expected  []string
+	}{
+		{namespace: "kubernetes", expected: []string{"k8s/service.yaml"}},
+		{namespace: "docker", expected: []string{"Dockerfile"}},
+	}
+
+	// The subtests run in parallel, as the selectors are evaluated on
+	// first use by whichever check comes first.
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.namespace, func(t *testing.T) {
+			t.Parallel()
+
+			results, err := engine.Check(ctx, configs, tt.namespace)
+			if err != nil {
+				t.Fatalf("check: %v", err)
+			}
+
+			var actual []string
+			for _, result := range results {
+				actual = append(actual, result.FileName)
+			}
+
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected results for %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFileMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	engine, err := Load(ctx, []string{"../examples/file-metadata/policy"})
+	if err != nil {
+		t.Fatalf("loading policies: %v", err)
+	}
+
+	configs, err := parser.ParseConfigurations([]string{"../examples/file-metadata/prod/deployment.yaml"})
+	if err != nil {
+		t.Fatalf("loading configs: %v", err)
+	}
+
+	results, err := engine.Check(ctx, map[string]interface{}{"prod/deployment.yaml": configs["../examples/file-metadata/prod/deployment.yaml"]}, "main")
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+
+	expected := []output.Result{{Message: "Deployment hello-kubernetes in prod/deployment.yaml (document 0) must have at least 3 replicas"}}
+	if !reflect.DeepEqual(results[0].Failures, expected) {
+		t.Errorf("expected failures %v, got %v", expected, results[0].Failures)
+	}
+
+	// The metadata of the file must not be left behind in the store.
+	txn, err := engine.Store().NewTransaction(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Store().Abort(ctx, txn)
+
+	if _, err := engine.Store().Read(ctx, txn, conftestPath); !storage.IsNotFound(err) {
+		t.Errorf("expected data.conftest to be removed from the store, got: %v", err)
+	}
+}
+
+func TestFileMetadataDocument(t *testing.T) {
+	ctx := context.Background()
+
+	policy := `package main
+
+deny[msg] {
+	conftest := data.conftest
+	msg := sprintf("%s %s", [conftest.params.env, conftest.file.name])
+}
+
+deny[msg] {
+	msg := sprintf("%d documents", [count(data.conftest)])
+}`
+
+	engine, err := New(ctx, WithModules(map[string]string{"policy/main.rego": policy}))
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	if err := engine.SetParams(ctx, map[string]interface{}{"env": "staging"}); err != nil {
+		t.Fatalf("set params: %v", err)
+	}
+
+	results, err := engine.Check(ctx, map[string]interface{}{"prod/deployment.yaml": map[string]interface{}{}}, "main")
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+
+	var actual []string
+	for _, failure := range results[0].Failures {
+		actual = append(actual, failure.Message)
+	}
+	sort.Strings(actual)
+
+	expected := []string{"2 documents", "staging deployment.yaml"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected failures %v, got %v", expected, actual)
+	}
+}
+
+func TestParams(t *testing.T) {
+	ctx := context.Background()
+
+	engine, err := Load(ctx, []string{"../examples/params/policy"})
+	if err != nil {
+		t.Fatalf("loading policies: %v", err)
+	}
+
+	params := map[string]interface{}{"env": "staging", "max_replicas": 2, "min_replicas": 1}
+	if err := engine.SetParams(ctx, params); err != nil {
+		t.Fatalf("set params: %v", err)
+	}
+
+	configs, err := parser.ParseConfigurations([]string{"../examples/params/deployment.yaml"})
+	if err != nil {
+		t.Fatalf("loading configs: %v", err)
+	}
+
+	// The params must still be available alongside the file metadata
+	// of each evaluation.
+	for i := 0; i < 2; i++ {
+		results, err := engine.Check(ctx, configs, "main")
+		if err != nil {
+			t.Fatalf("check: %v", err)
+		}
+
+		expected := []output.Result{{Message: "hello-kubernetes must not have more than 2 replicas in staging"}}
+		if !reflect.DeepEqual(results[0].Failures, expected) {
+			t.Errorf("expected failures %v, got %v", expected, results[0].Failures)
+		}
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	modules := map[string]string{
+		"policy/slow.rego": `package main
+
+deny[msg] {
+	a := numbers.range(1, 100000)[_]
+	b := numbers.range(1, 100000)[_]
+	a + b < 0
+	msg := "never reported"
+}
+
+warn[msg] {
+	input.replicas > 1
+	msg := "too many replicas"
+}`,
+	}
+
+	engine, err := New(ctx, WithModules(modules), WithQueryTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+
+	configs := map[string]interface{}{"deployment.yaml": map[string]interface{}{"replicas": 2}}
+	results, err := engine.Check(ctx, configs, "main")
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+
+	expectedErrors := []output.Result{{Message: "evaluating data.main.deny against deployment.yaml timed out after 50ms"}}
+	if !reflect.DeepEqual(results[0].Errors, expectedErrors) {
+		t.Errorf("expected errors %v, got %v", expectedErrors, results[0].Errors)
+	}
+
+	expectedWarnings := []output.Result{{Message: "too many replicas"}}
+	if !reflect.DeepEqual(results[0].Warnings, expectedWarnings) {
+		t.Errorf("expected warnings %v, got %v", expectedWarnings, results[0].Warnings)
+	}
+
+	if results[0].Successes != 0 {
+		t.Errorf("expected no successes, got %d", results[0].Successes)
+	}
+}
@@ -0,0 +1,221 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/conftest/parser"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/types"
+)
+
+// fileBuiltin reads a file relative to the directory of the file that is
+// being evaluated, for example the resources of a Kustomization:
+//
+//	resource := conftest.file(input.resources[_])
+//
+// Files in a format known to conftest are parsed with the matching parser,
+// while the contents of any other file, such as a README, are returned as
+// a string. Only the files within the roots of the engine can
+// be read, and the call is undefined for any other file.
+var fileBuiltin = &rego.Function{
+	Name:    "conftest.file",
+	Decl:    types.NewFunction(types.Args(types.S), types.A),
+	Memoize: true,
+}
+
+func init() {
+	rego.RegisterBuiltin1(fileBuiltin, readFile)
+}
+
+type fileContextKey struct{}
+
+// fileContext is passed to the conftest.file builtin through the context
+// of the evaluation of a single file.
+type fileContext struct {
+	dir   string
+	roots []string
+	cache *fileCache
+}
+
+// withFileContext returns a context from which the conftest.file builtin
+// reads the files that are relative to the file at the given path.
+func (e *Engine) withFileContext(ctx context.Context, path string) (context.Context, error) {
+	if path == "-" {
+		return ctx, nil
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("get abs: %w", err)
+	}
+
+	roots := e.roots
+	if len(roots) == 0 {
+		workingDirectory, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("get working directory: %w", err)
+		}
+
+		roots, err = resolveRoots([]string{workingDirectory})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return context.WithValue(ctx, fileContextKey{}, &fileContext{dir: dir, roots: roots, cache: e.files}), nil
+}
+
+// SetRoots sets the paths that the policies can read files from with the
+// conftest.file builtin. For paths that are files, the directory of the
+// file is used. When no roots are set, the policies can read the files
+// within the working directory.
+func (e *Engine) SetRoots(paths []string) error {
+	roots, err := resolveRoots(paths)
+	if err != nil {
+		return err
+	}
+
+	e.roots = roots
+	return nil
+}
+
+// resolveRoots returns the absolute paths of the directories of the roots,
+// with any symbolic links resolved. Stdin is not a root.
+func resolveRoots(paths []string) ([]string, error) {
+	var roots []string
+	for _, path := range paths {
+		if path == "-" {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("get file info: %w", err)
+		}
+
+		if !info.IsDir() {
+			path = filepath.Dir(path)
+		}
+
+		root, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("get abs: %w", err)
+		}
+
+		root, err = filepath.EvalSymlinks(root)
+		if err != nil {
+			return nil, fmt.Errorf("eval symlinks: %w", err)
+		}
+
+		roots = append(roots, root)
+	}
+
+	return roots, nil
+}
+
+// fileCache caches the values of the files read by the conftest.file
+// builtin across evaluations, keyed by their resolved path.
+type fileCache struct {
+	mu     sync.Mutex
+	values map[string]fileCacheEntry
+}
+
+type fileCacheEntry struct {
+	value ast.Value
+	err   error
+}
+
+func (c *fileCache) get(path string) (ast.Value, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.values[path]; ok {
+		return entry.value, entry.err
+	}
+
+	value, err := parseFile(path)
+	c.values[path] = fileCacheEntry{value: value, err: err}
+
+	return value, err
+}
+
+func readFile(bctx rego.BuiltinContext, pathTerm *ast.Term) (*ast.Term, error) {
+	relPath, ok := pathTerm.Value.(ast.String)
+	if !ok {
+		return nil, fmt.Errorf("path must be a string")
+	}
+
+	fc, ok := bctx.Context.Value(fileContextKey{}).(*fileContext)
+	if !ok {
+		return nil, errors.New("no file is being evaluated")
+	}
+
+	if filepath.IsAbs(string(relPath)) {
+		return nil, fmt.Errorf("path %s must be relative", relPath)
+	}
+
+	path, err := filepath.EvalSymlinks(filepath.Join(fc.dir, filepath.FromSlash(string(relPath))))
+	if err != nil {
+		return nil, fmt.Errorf("eval symlinks: %w", err)
+	}
+
+	if !withinRoots(path, fc.roots) {
+		return nil, fmt.Errorf("path %s is outside of the tested paths", relPath)
+	}
+
+	value, err := fc.cache.get(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.NewTerm(value), nil
+}
+
+func withinRoots(path string, roots []string) bool {
+	for _, root := range roots {
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			continue
+		}
+
+		if relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseFile parses the file with the parser that matches its name or
+// extension. Files in an unknown format are returned as a string.
+func parseFile(path string) (ast.Value, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	if !parser.FileRecognized(path) {
+		return ast.String(contents), nil
+	}
+
+	p, err := parser.NewFromPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("new parser: %w", err)
+	}
+
+	value, err := unmarshalValue(p, contents)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,91 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/open-policy-agent/opa/rego"
+)
+
+func TestFileBuiltin(t *testing.T) {
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"policy/file.rego": `package main
+
+warn[msg] {
+	value := conftest.file(input.files[_])
+	msg := sprintf("%v", [value])
+}
+`,
+		"root/app/config.json": `{"port": 8080}`,
+		"root/app/README":      "hello",
+		"root/shared.yaml":     "shared: true\n",
+		"outside.json":         `{"secret": true}`,
+	})
+
+	if err := os.Symlink(filepath.Join(dir, "outside.json"), filepath.Join(dir, "root", "app", "link.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	engine, err := Load(ctx, []string{filepath.Join(dir, "policy")})
+	if err != nil {
+		t.Fatalf("loading policies: %v", err)
+	}
+
+	if err := engine.SetRoots([]string{filepath.Join(dir, "root")}); err != nil {
+		t.Fatalf("set roots: %v", err)
+	}
+
+	testCases := []struct {
+		file     string
+		expected []string
+	}{
+		{file: "config.json", expected: []string{`{"port": 8080}`}},
+		{file: "README", expected: []string{"hello"}},
+		{file: "../shared.yaml", expected: []string{`{"shared": true}`}},
+		{file: "../../outside.json"},
+		{file: "link.json"},
+		{file: "missing.json"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.file, func(t *testing.T) {
+			input := map[string]interface{}{"files": []interface{}{testCase.file}}
+			configs := map[string]interface{}{filepath.Join(dir, "root", "app", "input.json"): input}
+
+			results, err := engine.Check(ctx, configs, "main")
+			if err != nil {
+				t.Fatalf("check: %v", err)
+			}
+
+			var messages []string
+			for _, warning := range results[0].Warnings {
+				messages = append(messages, warning.Message)
+			}
+			sort.Strings(messages)
+
+			if !reflect.DeepEqual(messages, testCase.expected) {
+				t.Errorf("expected %v, got %v", testCase.expected, messages)
+			}
+		})
+	}
+}
+
+func TestFileBuiltin_NoFile(t *testing.T) {
+	// Outside of the evaluation of a file, such as when the configurations
+	// are combined, there is no directory to read the files from.
+	resultSet, err := rego.New(rego.Query(`conftest.file("policy.rego")`)).Eval(context.Background())
+	if err != nil {
+		t.Fatalf("eval: %v", err)
+	}
+
+	if len(resultSet) != 0 {
+		t.Errorf("expected the call to be undefined, got %v", resultSet)
+	}
+}
@@ -0,0 +1,152 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-policy-agent/conftest/parser"
+
+	"github.com/open-policy-agent/opa/storage"
+)
+
+// conftestPath is the path of the data document under which conftest
+// exposes information about the current evaluation to the policies.
+var conftestPath = storage.MustParsePath("/conftest")
+
+// fileMetadata describes the file that is being evaluated. It is exposed to
+// the policies under data.conftest.file so that the input is left unchanged.
+type fileMetadata struct {
+	path          string
+	parser        string
+	documentIndex int
+}
+
+func (e *Engine) newFileMetadata(path string, documentIndex int) *fileMetadata {
+	parserName, ok := e.parsers[path]
+	if !ok {
+		parserName, _ = parser.NameFromPath(path)
+	}
+
+	return &fileMetadata{
+		path:          path,
+		parser:        parserName,
+		documentIndex: documentIndex,
+	}
+}
+
+// value returns the document that is exposed at data.conftest.file. The
+// extension does not include the leading dot (ex: yaml).
+func (f *fileMetadata) value() map[string]interface{} {
+	var name, dir, ext string
+	if f.path != "-" {
+		name = filepath.Base(f.path)
+		dir = filepath.ToSlash(filepath.Dir(f.path))
+		ext = strings.TrimPrefix(filepath.Ext(f.path), ".")
+	}
+
+	return map[string]interface{}{
+		"path":           filepath.ToSlash(f.path),
+		"name":           name,
+		"dir":            dir,
+		"ext":            ext,
+		"parser":         f.parser,
+		"document_index": f.documentIndex,
+	}
+}
+
+// fileStore is a read-only view of the store of the engine that includes the
+// metadata of a file at data.conftest.file. The metadata is never written to
+// the underlying store, so that queries only need a read transaction and can
+// be evaluated concurrently, even when the store is provided by an embedder.
+type fileStore struct {
+	storage.Store
+	file map[string]interface{}
+}
+
+var fileMetadataPath = append(append(storage.Path{}, conftestPath...), "file")
+
+func newFileStore(store storage.Store, file *fileMetadata) *fileStore {
+	return &fileStore{
+		Store: store,
+		file:  file.value(),
+	}
+}
+
+// Read reads the document at the given path. Reads of the metadata of the
+// file, or of the documents that contain it, include the metadata.
+func (s *fileStore) Read(ctx context.Context, txn storage.Transaction, path storage.Path) (interface{}, error) {
+	if path.HasPrefix(fileMetadataPath) {
+		return readDocument(s.file, path, len(fileMetadataPath))
+	}
+
+	if !fileMetadataPath.HasPrefix(path) {
+		return s.Store.Read(ctx, txn, path)
+	}
+
+	document, err := s.Store.Read(ctx, txn, path)
+	if err != nil && !storage.IsNotFound(err) {
+		return nil, err
+	}
+
+	return withDocument(document, fileMetadataPath[len(path):], s.file), nil
+}
+
+// readDocument returns the value at the given path within the document, where
+// the first depth keys of the path lead to the document itself.
+func readDocument(document interface{}, path storage.Path, depth int) (interface{}, error) {
+	value := document
+	for _, key := range path[depth:] {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, &storage.Error{Code: storage.NotFoundErr, Message: path.String() + ": document missing"}
+		}
+
+		if value, ok = object[key]; !ok {
+			return nil, &storage.Error{Code: storage.NotFoundErr, Message: path.String() + ": document missing"}
+		}
+	}
+
+	return value, nil
+}
+
+// withDocument returns a copy of the document in which the value is set at the
+// given path. Only the objects along the path are copied.
+func withDocument(document interface{}, path storage.Path, value interface{}) interface{} {
+	if len(path) == 0 {
+		return value
+	}
+
+	object := make(map[string]interface{})
+	if existing, ok := document.(map[string]interface{}); ok {
+		for key, value := range existing {
+			object[key] = value
+		}
+	}
+	object[path[0]] = withDocument(object[path[0]], path[1:], value)
+
+	return object
+}
+
+// writeConftestDocument writes the value to data.conftest.<key>, creating
+// the conftest document when it does not exist yet.
+func writeConftestDocument(ctx context.Context, store storage.Store, txn storage.Transaction, key string, value interface{}) error {
+	if _, err := store.Read(ctx, txn, conftestPath); err != nil {
+		if !storage.IsNotFound(err) {
+			return fmt.Errorf("read conftest document: %w", err)
+		}
+
+		if err := store.Write(ctx, txn, storage.AddOp, conftestPath, map[string]interface{}{}); err != nil {
+			return fmt.Errorf("write conftest document: %w", err)
+		}
+	}
+
+	documentPath := append(storage.Path{}, conftestPath...)
+	documentPath = append(documentPath, key)
+	if err := store.Write(ctx, txn, storage.AddOp, documentPath, value); err != nil {
+		return err
+	}
+
+	return nil
+}
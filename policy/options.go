@@ -0,0 +1,128 @@
+package policy
+
+import (
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/storage"
+)
+
+// Option configures an Engine that is created with New.
+type Option func(*options)
+
+type options struct {
+	policyPaths  []string
+	modules      map[string]string
+	dataPaths    []string
+	nestedData   bool
+	store        storage.Store
+	capabilities *ast.Capabilities
+	builtins     []Builtin
+	trace        bool
+	runtime      *ast.Term
+	queryTimeout time.Duration
+	profile      bool
+	coverage     bool
+}
+
+// WithPolicies loads the policies at the given paths. Directories are
+// recursively searched for Rego files.
+func WithPolicies(paths ...string) Option {
+	return func(o *options) {
+		o.policyPaths = append(o.policyPaths, paths...)
+	}
+}
+
+// WithModules adds policies from memory, keyed by the name of the module
+// (ex: policy/deny.rego), so that no policies have to be read from disk.
+func WithModules(modules map[string]string) Option {
+	return func(o *options) {
+		if o.modules == nil {
+			o.modules = make(map[string]string)
+		}
+
+		for name, module := range modules {
+			o.modules[name] = module
+		}
+	}
+}
+
+// WithData loads the data documents at the given paths. The paths have the
+// same syntax as the data flag, so each path can be rooted at a prefix.
+func WithData(paths ...string) Option {
+	return func(o *options) {
+		o.dataPaths = append(o.dataPaths, paths...)
+	}
+}
+
+// WithNestedData places the data documents found in the subdirectories of a
+// data path at the path of their directory, relative to the data path, instead
+// of merging all of the documents at the prefix of the data path.
+func WithNestedData() Option {
+	return func(o *options) {
+		o.nestedData = true
+	}
+}
+
+// WithStore uses the given store for the data of the policies, instead of an
+// in-memory store. Documents loaded with WithData are written to the store.
+func WithStore(store storage.Store) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+// WithCapabilities compiles the policies with the given capabilities, so that
+// the policies may only use the builtins that are part of the capabilities.
+func WithCapabilities(capabilities *ast.Capabilities) Option {
+	return func(o *options) {
+		o.capabilities = capabilities
+	}
+}
+
+// WithBuiltins adds custom builtins that the policies of the engine can call.
+func WithBuiltins(builtins ...Builtin) Option {
+	return func(o *options) {
+		o.builtins = append(o.builtins, builtins...)
+	}
+}
+
+// WithTracing enables the tracing of the evaluation of the policies.
+func WithTracing() Option {
+	return func(o *options) {
+		o.trace = true
+	}
+}
+
+// WithRuntime sets the value that is returned by opa.runtime(), instead
+// of the runtime that is built from the environment variables.
+func WithRuntime(runtime *ast.Term) Option {
+	return func(o *options) {
+		o.runtime = runtime
+	}
+}
+
+// WithQueryTimeout limits the time that the evaluation of a single query,
+// such as data.main.deny for one file, may take. A query that times out is
+// reported as an error of the file that was being evaluated.
+func WithQueryTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.queryTimeout = timeout
+	}
+}
+
+// WithProfiling records the time spent evaluating the expressions and rules
+// of the policies, which is returned by the Profile method of the engine.
+func WithProfiling() Option {
+	return func(o *options) {
+		o.profile = true
+	}
+}
+
+// WithCoverage records the lines of the policies that are evaluated, which
+// are returned by the Coverage method of the engine.
+func WithCoverage() Option {
+	return func(o *options) {
+		o.coverage = true
+	}
+}
@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/open-policy-agent/conftest/output"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage/inmem"
+)
+
+func TestNew(t *testing.T) {
+	ctx := context.Background()
+
+	modules := map[string]string{
+		"policy/replicas.rego": `package main
+
+deny[msg] {
+	input.replicas > data.limits.max_replicas
+	msg := sprintf("%s must not have more than %d replicas in %s", [input.name, data.limits.max_replicas, opa.runtime().env.STAGE])
+}`,
+	}
+
+	store := inmem.NewFromObject(map[string]interface{}{
+		"limits": map[string]interface{}{"max_replicas": 2},
+	})
+
+	runtime := ast.ObjectTerm(ast.Item(ast.StringTerm("env"), ast.ObjectTerm(ast.Item(ast.StringTerm("STAGE"), ast.StringTerm("staging")))))
+
+	engine, err := New(ctx, WithModules(modules), WithStore(store), WithRuntime(runtime))
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+
+	if engine.Store() != store {
+		t.Errorf("expected the engine to use the custom store")
+	}
+
+	if _, ok := engine.Policies()["policy/replicas.rego"]; !ok {
+		t.Errorf("expected policy/replicas.rego in the policies, got %v", engine.Policies())
+	}
+
+	configs := map[string]interface{}{
+		"deployment.yaml": map[string]interface{}{"name": "web", "replicas": 3},
+	}
+
+	results, err := engine.Check(ctx, configs, "main")
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+
+	expected := []output.Result{{Message: "web must not have more than 2 replicas in staging"}}
+	if !reflect.DeepEqual(results[0].Failures, expected) {
+		t.Errorf("expected failures %v, got %v", expected, results[0].Failures)
+	}
+}
+
+func TestNew_DataInStore(t *testing.T) {
+	ctx := context.Background()
+
+	store := inmem.NewFromObject(map[string]interface{}{
+		"existing": map[string]interface{}{"value": true},
+	})
+
+	engine, err := New(ctx,
+		WithPolicies("../examples/data/policy"),
+		WithData("../examples/data/exclusions"),
+		WithStore(store),
+	)
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+
+	for _, query := range []string{"data.existing.value", "data.services"} {
+		resultSet, err := engine.newRego(rego.Query(query)).Eval(ctx)
+		if err != nil {
+			t.Fatalf("eval %s: %v", query, err)
+		}
+
+		if len(resultSet) == 0 {
+			t.Errorf("expected %s to be defined in the store", query)
+		}
+	}
+}
+
+func TestNew_NoPolicies(t *testing.T) {
+	if _, err := New(context.Background()); err == nil {
+		t.Errorf("expected an error when no policies are loaded")
+	}
+}
+
+func TestNew_InvalidModule(t *testing.T) {
+	modules := map[string]string{"invalid.rego": "package"}
+	if _, err := New(context.Background(), WithModules(modules)); err == nil {
+		t.Errorf("expected an error for an invalid module")
+	}
+}
@@ -0,0 +1,233 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/profiler"
+)
+
+// Profile is the profile of the evaluation of the policies, aggregated
+// across all of the files that were checked by the engine.
+type Profile struct {
+	Expressions []ExpressionProfile `json:"expressions"`
+	Rules       []RuleProfile       `json:"rules"`
+	Evaluations []EvaluationProfile `json:"evaluations"`
+}
+
+// ExpressionProfile is the time spent evaluating a single expression of a
+// policy, and the number of times that it was evaluated (NumEval) and that
+// it was evaluated again while backtracking (NumRedo).
+type ExpressionProfile struct {
+	Location   string        `json:"location"`
+	Expression string        `json:"expression"`
+	Time       time.Duration `json:"time_ns"`
+	NumEval    int           `json:"num_eval"`
+	NumRedo    int           `json:"num_redo"`
+}
+
+// RuleProfile is the wall time spent evaluating a rule, such as deny,
+// including its exceptions, and the number of files that it was
+// evaluated against.
+type RuleProfile struct {
+	Namespace string        `json:"namespace"`
+	Rule      string        `json:"rule"`
+	Location  string        `json:"location"`
+	Time      time.Duration `json:"time_ns"`
+	NumEval   int           `json:"num_eval"`
+}
+
+// EvaluationProfile is the wall time spent evaluating a rule, including its
+// exceptions, against a file.
+type EvaluationProfile struct {
+	Namespace string        `json:"namespace"`
+	Rule      string        `json:"rule"`
+	File      string        `json:"file"`
+	Time      time.Duration `json:"time_ns"`
+}
+
+type evaluationKey struct {
+	namespace string
+	rule      string
+	file      string
+}
+
+// profileRecorder records the profiles of the queries of an engine. A nil
+// recorder does not record anything, so that profiling can be disabled.
+type profileRecorder struct {
+	mu          sync.Mutex
+	expressions map[string]*ExpressionProfile
+	evaluations map[evaluationKey]time.Duration
+}
+
+func newProfileRecorder() *profileRecorder {
+	return &profileRecorder{
+		expressions: make(map[string]*ExpressionProfile),
+		evaluations: make(map[evaluationKey]time.Duration),
+	}
+}
+
+// recordExpressions adds the statistics of the profiler of a single query.
+// Each query is profiled on its own so that the time between queries is
+// not attributed to the last expression of the previous query.
+func (r *profileRecorder) recordExpressions(p *profiler.Profiler) {
+	if r == nil || p == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stats := range p.ReportTopNResults(0, nil) {
+		// The expressions of the queries of conftest itself, such as
+		// data.main.deny, are not part of any of the policies.
+		if stats.Location == nil || stats.Location.File == "" {
+			continue
+		}
+
+		key := fmt.Sprintf("%s:%d:%d", stats.Location.File, stats.Location.Row, stats.Location.Col)
+		expression, ok := r.expressions[key]
+		if !ok {
+			expression = &ExpressionProfile{
+				Location:   fmt.Sprintf("%s:%d", stats.Location.File, stats.Location.Row),
+				Expression: string(stats.Location.Text),
+			}
+			r.expressions[key] = expression
+		}
+
+		expression.Time += time.Duration(stats.ExprTimeNs)
+		expression.NumEval += stats.NumEval
+		expression.NumRedo += stats.NumRedo
+	}
+}
+
+// recordEvaluation adds the wall time of the evaluation of a rule against a file.
+func (r *profileRecorder) recordEvaluation(namespace string, rule string, file string, duration time.Duration) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.evaluations[evaluationKey{namespace: namespace, rule: rule, file: file}] += duration
+}
+
+// Profile returns the profile of the evaluation of the policies, with the
+// slowest expressions, rules and evaluations first. At most limit entries
+// of each are returned, unless the limit is zero. The profile is empty
+// unless the engine was created with WithProfiling.
+func (e *Engine) Profile(limit int) Profile {
+	var profile Profile
+	if e.profiling == nil {
+		return profile
+	}
+
+	e.profiling.mu.Lock()
+	defer e.profiling.mu.Unlock()
+
+	for _, expression := range e.profiling.expressions {
+		profile.Expressions = append(profile.Expressions, *expression)
+	}
+
+	sort.Slice(profile.Expressions, func(i, j int) bool {
+		if profile.Expressions[i].Time != profile.Expressions[j].Time {
+			return profile.Expressions[i].Time > profile.Expressions[j].Time
+		}
+
+		return profile.Expressions[i].Location < profile.Expressions[j].Location
+	})
+
+	rules := make(map[string]*RuleProfile)
+	for key, duration := range e.profiling.evaluations {
+		profile.Evaluations = append(profile.Evaluations, EvaluationProfile{
+			Namespace: key.namespace,
+			Rule:      key.rule,
+			File:      key.file,
+			Time:      duration,
+		})
+
+		ruleKey := key.namespace + "." + key.rule
+		rule, ok := rules[ruleKey]
+		if !ok {
+			rule = &RuleProfile{
+				Namespace: key.namespace,
+				Rule:      key.rule,
+				Location:  e.ruleLocation(key.namespace, key.rule),
+			}
+			rules[ruleKey] = rule
+		}
+
+		rule.Time += duration
+		rule.NumEval++
+	}
+
+	sort.Slice(profile.Evaluations, func(i, j int) bool {
+		a, b := profile.Evaluations[i], profile.Evaluations[j]
+		if a.Time != b.Time {
+			return a.Time > b.Time
+		}
+
+		return a.Namespace+"."+a.Rule+" "+a.File < b.Namespace+"."+b.Rule+" "+b.File
+	})
+
+	for _, rule := range rules {
+		profile.Rules = append(profile.Rules, *rule)
+	}
+
+	sort.Slice(profile.Rules, func(i, j int) bool {
+		a, b := profile.Rules[i], profile.Rules[j]
+		if a.Time != b.Time {
+			return a.Time > b.Time
+		}
+
+		return a.Namespace+"."+a.Rule < b.Namespace+"."+b.Rule
+	})
+
+	if limit > 0 {
+		if len(profile.Expressions) > limit {
+			profile.Expressions = profile.Expressions[:limit]
+		}
+
+		if len(profile.Rules) > limit {
+			profile.Rules = profile.Rules[:limit]
+		}
+
+		if len(profile.Evaluations) > limit {
+			profile.Evaluations = profile.Evaluations[:limit]
+		}
+	}
+
+	return profile
+}
+
+// ruleLocation returns the location of the first definition of the rule
+// within the namespace.
+func (e *Engine) ruleLocation(namespace string, rule string) string {
+	var first *ast.Location
+	for _, module := range e.Modules() {
+		if strings.Replace(module.Package.Path.String(), "data.", "", 1) != namespace {
+			continue
+		}
+
+		for _, r := range module.Rules {
+			if r.Head.Name.String() != rule || r.Location == nil {
+				continue
+			}
+
+			if first == nil || r.Location.File < first.File || (r.Location.File == first.File && r.Location.Row < first.Row) {
+				first = r.Location
+			}
+		}
+	}
+
+	if first == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%s:%d", first.File, first.Row)
+}
@@ -0,0 +1,89 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-policy-agent/conftest/parser"
+)
+
+func TestProfile(t *testing.T) {
+	ctx := context.Background()
+
+	engine, err := New(ctx, WithPolicies("../examples/kubernetes/policy"), WithProfiling())
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+
+	configs, err := parser.ParseConfigurations([]string{"../examples/kubernetes/deployment.yaml", "../examples/kubernetes/service.yaml"})
+	if err != nil {
+		t.Fatalf("loading configs: %v", err)
+	}
+
+	if _, err := engine.Check(ctx, configs, "main"); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+
+	profile := engine.Profile(0)
+	if len(profile.Expressions) == 0 {
+		t.Errorf("expected the profile to contain expressions")
+	}
+
+	for _, expression := range profile.Expressions {
+		if expression.Location == "" || expression.NumEval == 0 {
+			t.Errorf("expected expression %q to have a location and evaluations, got %+v", expression.Expression, expression)
+		}
+	}
+
+	var deny *RuleProfile
+	for i := range profile.Rules {
+		if profile.Rules[i].Rule == "deny" {
+			deny = &profile.Rules[i]
+		}
+	}
+
+	if deny == nil {
+		t.Fatalf("expected the profile to contain the deny rule, got %+v", profile.Rules)
+	}
+
+	if deny.Location != "../examples/kubernetes/policy/deny.rego:7" {
+		t.Errorf("expected the location of the first deny rule, got %s", deny.Location)
+	}
+
+	if deny.NumEval != 2 {
+		t.Errorf("expected deny to be evaluated against 2 files, got %d", deny.NumEval)
+	}
+
+	// The deny, violation and warn rules are each evaluated against both files.
+	if len(profile.Evaluations) != 6 {
+		t.Errorf("expected 6 evaluations, got %d", len(profile.Evaluations))
+	}
+
+	limited := engine.Profile(1)
+	if len(limited.Expressions) != 1 || len(limited.Rules) != 1 || len(limited.Evaluations) != 1 {
+		t.Errorf("expected a single entry of each kind, got %+v", limited)
+	}
+}
+
+func TestProfile_Disabled(t *testing.T) {
+	ctx := context.Background()
+
+	engine, err := New(ctx, WithPolicies("../examples/kubernetes/policy"))
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+
+	configs, err := parser.ParseConfigurations([]string{"../examples/kubernetes/deployment.yaml"})
+	if err != nil {
+		t.Fatalf("loading configs: %v", err)
+	}
+
+	if _, err := engine.Check(ctx, configs, "main"); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+
+	profile := engine.Profile(0)
+	if len(profile.Expressions) > 0 || len(profile.Rules) > 0 || len(profile.Evaluations) > 0 {
+		t.Errorf("expected an empty profile, got %+v", profile)
+	}
+}
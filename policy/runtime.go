@@ -0,0 +1,172 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// SetRuntimeEnv sets the patterns of the environment variables that are
+// exposed to the policies through opa.runtime().env (ex: CONFTEST_*). By
+// default, no environment variables are exposed, as policies downloaded
+// from a remote location could otherwise read secrets such as credentials.
+func (e *Engine) SetRuntimeEnv(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid runtime env pattern %q: %w", pattern, err)
+		}
+	}
+
+	e.runtimeEnv = patterns
+	return nil
+}
+
+// runtimeEnvAllowed reports whether the environment variable is exposed
+// to the policies.
+func (e *Engine) runtimeEnvAllowed(name string) bool {
+	for _, pattern := range e.runtimeEnv {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runtimeEnvObject returns the environment variables that are exposed to the policies.
+func (e *Engine) runtimeEnvObject() ast.Object {
+	env := ast.NewObject()
+	for _, pair := range os.Environ() {
+		parts := strings.SplitN(pair, "=", 2)
+		if !e.runtimeEnvAllowed(parts[0]) {
+			continue
+		}
+
+		if len(parts) == 1 {
+			env.Insert(ast.StringTerm(parts[0]), ast.NullTerm())
+		} else if len(parts) > 1 {
+			env.Insert(ast.StringTerm(parts[0]), ast.StringTerm(parts[1]))
+		}
+	}
+
+	return env
+}
+
+// BlockedEnvRead is a read of an environment variable through opa.runtime()
+// that is not exposed to the policies.
+type BlockedEnvRead struct {
+	Policy string
+	Name   string
+}
+
+// BlockedEnvReads returns the environment variables that the policies read
+// through opa.runtime().env, but that are not exposed to them. Only reads
+// of a variable with a constant name can be detected, for example:
+//
+//	rt := opa.runtime()
+//	token := rt.env.GITHUB_TOKEN
+func (e *Engine) BlockedEnvReads() []BlockedEnvRead {
+	var reads []BlockedEnvRead
+	for policyPath, module := range e.modules {
+		for _, name := range envReads(module) {
+			if !e.runtimeEnvAllowed(name) {
+				reads = append(reads, BlockedEnvRead{Policy: policyPath, Name: name})
+			}
+		}
+	}
+
+	sort.Slice(reads, func(i, j int) bool {
+		if reads[i].Policy != reads[j].Policy {
+			return reads[i].Policy < reads[j].Policy
+		}
+
+		return reads[i].Name < reads[j].Name
+	})
+
+	return reads
+}
+
+// envReads returns the names of the environment variables that the module
+// reads through opa.runtime(). The result of opa.runtime(), or of its env
+// key, can be assigned to a variable or a rule before it is read.
+func envReads(module *ast.Module) []string {
+	runtimeVars := make(map[ast.Var]bool)
+	envVars := make(map[ast.Var]bool)
+
+	assign := func(target *ast.Term, value *ast.Term) {
+		v, ok := target.Value.(ast.Var)
+		if !ok {
+			return
+		}
+
+		if isRuntimeCall(value) {
+			runtimeVars[v] = true
+		} else if ref, ok := value.Value.(ast.Ref); ok && len(ref) == 2 && isRuntime(ref[0], runtimeVars) && ref[1].Equal(ast.StringTerm("env")) {
+			envVars[v] = true
+		}
+	}
+
+	// The assignments are collected first so that reads of rules that are
+	// defined later in the module are found as well.
+	for _, rule := range module.Rules {
+		if rule.Head.Value != nil {
+			assign(ast.VarTerm(string(rule.Head.Name)), rule.Head.Value)
+		}
+	}
+
+	ast.WalkExprs(module, func(expr *ast.Expr) bool {
+		if !expr.IsAssignment() && !expr.IsEquality() {
+			return false
+		}
+
+		operands := expr.Operands()
+		assign(operands[0], operands[1])
+		assign(operands[1], operands[0])
+		return false
+	})
+
+	names := make(map[string]bool)
+	ast.WalkRefs(module, func(ref ast.Ref) bool {
+		var nameTerm *ast.Term
+		switch {
+		case len(ref) >= 3 && isRuntime(ref[0], runtimeVars) && ref[1].Equal(ast.StringTerm("env")):
+			nameTerm = ref[2]
+		case len(ref) >= 2 && envVars[varOf(ref[0])]:
+			nameTerm = ref[1]
+		default:
+			return false
+		}
+
+		if name, ok := nameTerm.Value.(ast.String); ok {
+			names[string(name)] = true
+		}
+
+		return false
+	})
+
+	var reads []string
+	for name := range names {
+		reads = append(reads, name)
+	}
+	sort.Strings(reads)
+
+	return reads
+}
+
+func isRuntime(term *ast.Term, runtimeVars map[ast.Var]bool) bool {
+	return isRuntimeCall(term) || runtimeVars[varOf(term)]
+}
+
+func isRuntimeCall(term *ast.Term) bool {
+	call, ok := term.Value.(ast.Call)
+	return ok && len(call) > 0 && call[0].String() == ast.OPARuntime.Name
+}
+
+func varOf(term *ast.Term) ast.Var {
+	v, _ := term.Value.(ast.Var)
+	return v
+}
@@ -0,0 +1,70 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+func TestRuntimeEnv(t *testing.T) {
+	os.Setenv("CONFTEST_RUNTIME_TEST", "exposed")
+	os.Setenv("RUNTIME_TEST_TOKEN", "secret")
+	defer os.Unsetenv("CONFTEST_RUNTIME_TEST")
+	defer os.Unsetenv("RUNTIME_TEST_TOKEN")
+
+	engine, err := Load(context.Background(), []string{"../examples/runtime-env/policy"})
+	if err != nil {
+		t.Fatalf("loading policies: %v", err)
+	}
+
+	env := engine.Runtime().Value.(ast.Object).Get(ast.StringTerm("env")).Value.(ast.Object)
+	if env.Len() != 0 {
+		t.Errorf("expected no environment variables by default, got %v", env)
+	}
+
+	if err := engine.SetRuntimeEnv([]string{"CONFTEST_*"}); err != nil {
+		t.Fatalf("set runtime env: %v", err)
+	}
+
+	env = engine.Runtime().Value.(ast.Object).Get(ast.StringTerm("env")).Value.(ast.Object)
+	if value := env.Get(ast.StringTerm("CONFTEST_RUNTIME_TEST")); value == nil || !value.Equal(ast.StringTerm("exposed")) {
+		t.Errorf("expected CONFTEST_RUNTIME_TEST to be exposed, got %v", env)
+	}
+
+	if value := env.Get(ast.StringTerm("RUNTIME_TEST_TOKEN")); value != nil {
+		t.Errorf("expected RUNTIME_TEST_TOKEN not to be exposed, got %v", value)
+	}
+
+	if err := engine.SetRuntimeEnv([]string{"[CONFTEST"}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestEnvReads(t *testing.T) {
+	module := ast.MustParseModule(`package main
+
+runtime := opa.runtime()
+
+env := opa.runtime().env
+
+deny[msg] {
+	runtime.env.FROM_RULE
+	env.FROM_ENV_RULE
+	opa.runtime().env.FROM_CALL
+	rt := opa.runtime()
+	rt.env.FROM_VAR
+	input.env.NOT_RUNTIME
+	name := "DYNAMIC"
+	rt.env[name]
+	msg := "read"
+}
+`)
+
+	expected := []string{"FROM_CALL", "FROM_ENV_RULE", "FROM_RULE", "FROM_VAR"}
+	if reads := envReads(module); !reflect.DeepEqual(reads, expected) {
+		t.Errorf("expected reads %v, got %v", expected, reads)
+	}
+}
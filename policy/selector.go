@@ -0,0 +1,194 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/open-policy-agent/conftest/internal/gitignore"
+	"github.com/open-policy-agent/conftest/parser"
+
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// selectorRule is the name of the rule that a package can define to select
+// the files its policies apply to. For example:
+//
+//	__conftest__ := {"files": ["**/Dockerfile*"], "parsers": ["dockerfile"]}
+const selectorRule = "__conftest__"
+
+// selector selects the files that the policies of a namespace apply to.
+// A file is selected when it matches any of the file patterns and was
+// parsed by any of the parsers. An empty list selects all of the files.
+type selector struct {
+	files   []gitignore.Pattern
+	parsers []string
+}
+
+func (s *selector) selects(filePath string, parserName string) bool {
+	if s == nil {
+		return true
+	}
+
+	if len(s.files) > 0 {
+		var matched bool
+		relPath := selectorPath(filePath)
+		for _, pattern := range s.files {
+			if pattern.Match(relPath, false) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	if len(s.parsers) > 0 && !contains(s.parsers, parserName) {
+		return false
+	}
+
+	return true
+}
+
+// selectorPath returns the path that is matched against the file patterns of
+// a selector. Similar to the rules in the configuration file, the patterns are
+// relative to the working directory, so absolute paths are made relative to it.
+func selectorPath(filePath string) string {
+	if filepath.IsAbs(filePath) {
+		if workingDirectory, err := os.Getwd(); err == nil {
+			if relPath, err := filepath.Rel(workingDirectory, filePath); err == nil {
+				filePath = relPath
+			}
+		}
+	}
+
+	return path.Clean(filepath.ToSlash(filePath))
+}
+
+// applies reports whether the policies of the namespace apply to the
+// file at the given path.
+func (e *Engine) applies(ctx context.Context, namespace string, filePath string) (bool, error) {
+	s, err := e.selector(ctx, namespace)
+	if err != nil {
+		return false, fmt.Errorf("selector: %w", err)
+	}
+
+	if s == nil {
+		return true, nil
+	}
+
+	parserName, ok := e.parsers[filePath]
+	if !ok {
+		parserName, _ = parser.NameFromPath(filePath)
+	}
+
+	return s.selects(filePath, parserName), nil
+}
+
+// selector returns the selector of the namespace, or nil when the
+// namespace does not define one. Selectors are only evaluated once.
+func (e *Engine) selector(ctx context.Context, namespace string) (*selector, error) {
+	e.selectorsMu.Lock()
+	defer e.selectorsMu.Unlock()
+
+	if s, ok := e.selectors[namespace]; ok {
+		return s, nil
+	}
+
+	var defined bool
+	for _, module := range e.Modules() {
+		if module.Package.Path.String() != "data."+namespace {
+			continue
+		}
+
+		for _, rule := range module.Rules {
+			if rule.Head.Name.String() == selectorRule {
+				defined = true
+			}
+		}
+	}
+
+	var s *selector
+	if defined {
+		var err error
+		s, err = e.evalSelector(ctx, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("%s in %s: %w", selectorRule, namespace, err)
+		}
+	}
+
+	if e.selectors == nil {
+		e.selectors = make(map[string]*selector)
+	}
+	e.selectors[namespace] = s
+
+	return s, nil
+}
+
+func (e *Engine) evalSelector(ctx context.Context, namespace string) (*selector, error) {
+	resultSet, err := e.newRego(rego.Query(fmt.Sprintf("data.%s.%s", namespace, selectorRule))).Eval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating selector: %w", err)
+	}
+
+	if len(resultSet) == 0 || len(resultSet[0].Expressions) == 0 {
+		return nil, nil
+	}
+
+	value, ok := resultSet[0].Expressions[0].Value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be an object")
+	}
+
+	files, err := stringList(value, "files")
+	if err != nil {
+		return nil, err
+	}
+
+	parsers, err := stringList(value, "parsers")
+	if err != nil {
+		return nil, err
+	}
+
+	var s selector
+	for _, file := range files {
+		pattern, err := gitignore.Compile("", file)
+		if err != nil {
+			return nil, fmt.Errorf("compile pattern %q: %w", file, err)
+		}
+
+		s.files = append(s.files, pattern)
+	}
+	s.parsers = parsers
+
+	return &s, nil
+}
+
+// stringList returns the list of strings under the given key of the object.
+func stringList(object map[string]interface{}, key string) ([]string, error) {
+	value, ok := object[key]
+	if !ok {
+		return nil, nil
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list", key)
+	}
+
+	var list []string
+	for _, item := range items {
+		str, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must only contain strings", key)
+		}
+
+		list = append(list, str)
+	}
+
+	return list, nil
+}
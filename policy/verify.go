@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/tester"
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+// testTimeout is the time that a single test may take, which is
+// the same as the default of opa test.
+const testTimeout = 5 * time.Second
+
+// RunTests runs the Rego unit tests of the policies, which are the rules
+// that start with test_. Rules that start with todo_test_ are skipped.
+// Unlike opa test, the tests can use the custom builtins of the engine.
+func (e *Engine) RunTests(ctx context.Context) ([]*tester.Result, error) {
+	var moduleNames []string
+	for name := range e.compiler.Modules {
+		moduleNames = append(moduleNames, name)
+	}
+	sort.Strings(moduleNames)
+
+	var results []*tester.Result
+	for _, name := range moduleNames {
+		module := e.compiler.Modules[name]
+		for _, rule := range module.Rules {
+			ruleName := string(rule.Head.Name)
+			if !strings.HasPrefix(ruleName, tester.TestPrefix) && !strings.HasPrefix(ruleName, tester.SkipTestPrefix) {
+				continue
+			}
+
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("run tests: %w", err)
+			}
+
+			results = append(results, e.runTest(ctx, module, rule))
+		}
+	}
+
+	return results, nil
+}
+
+func (e *Engine) runTest(ctx context.Context, module *ast.Module, rule *ast.Rule) *tester.Result {
+	result := &tester.Result{
+		Location: rule.Loc(),
+		Package:  module.Package.Path.String(),
+		Name:     string(rule.Head.Name),
+	}
+
+	if strings.HasPrefix(result.Name, tester.SkipTestPrefix) {
+		result.Skip = true
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, testTimeout)
+	defer cancel()
+
+	options := []func(r *rego.Rego){
+		rego.Query(rule.Path().String()),
+	}
+
+	var tracer *topdown.BufferTracer
+	if e.trace {
+		tracer = topdown.NewBufferTracer()
+		options = append(options, rego.QueryTracer(tracer))
+	}
+
+	start := time.Now()
+	resultSet, err := e.newRego(options...).Eval(ctx)
+	result.Duration = time.Since(start)
+
+	if tracer != nil {
+		result.Trace = *tracer
+	}
+
+	if err != nil {
+		result.Error = err
+	} else if len(resultSet) == 0 {
+		result.Fail = true
+	} else if passed, ok := resultSet[0].Expressions[0].Value.(bool); !ok || !passed {
+		result.Fail = true
+	}
+
+	return result
+}
+
+// rewriteDuplicateTestNames renames the tests that have the same name in a
+// package to have a numbered suffix, so that each of them is run on its own.
+func rewriteDuplicateTestNames(compiler *ast.Compiler) *ast.Error {
+	count := make(map[string]int)
+	for _, module := range compiler.Modules {
+		for _, rule := range module.Rules {
+			name := rule.Head.Name.String()
+			if !strings.HasPrefix(name, tester.TestPrefix) {
+				continue
+			}
+
+			key := rule.Path().String()
+			if k, ok := count[key]; ok {
+				rule.Head.Name = ast.Var(fmt.Sprintf("%s#%02d", name, k))
+			}
+			count[key]++
+		}
+	}
+
+	return nil
+}
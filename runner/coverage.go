@@ -0,0 +1,268 @@
+package runner
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/open-policy-agent/opa/cover"
+)
+
+// The formats of the coverage report of a run.
+const (
+	CoverageJSON      = "json"
+	CoverageCobertura = "cobertura"
+	CoverageLCOV      = "lcov"
+)
+
+func validateCoverageFormat(format string) error {
+	switch format {
+	case "", CoverageJSON, CoverageCobertura, CoverageLCOV:
+		return nil
+	default:
+		return fmt.Errorf("unknown coverage format %q, valid formats are: %s, %s, %s", format, CoverageJSON, CoverageCobertura, CoverageLCOV)
+	}
+}
+
+// writeCoverageReport writes the coverage report to the file, or to w
+// when no file is given.
+func writeCoverageReport(w io.Writer, report cover.Report, format string, file string) error {
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return fmt.Errorf("create coverage file: %w", err)
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	return writeCoverage(w, report, format)
+}
+
+// CoverageThresholdError is returned by a run, together with its results,
+// when the coverage of the policies is below the coverage threshold.
+type CoverageThresholdError struct {
+	Coverage  float64
+	Threshold float64
+}
+
+func (e *CoverageThresholdError) Error() string {
+	return fmt.Sprintf("coverage of %.2f%% is below the threshold of %.2f%%", e.Coverage, e.Threshold)
+}
+
+// checkCoverageThreshold returns a *CoverageThresholdError when the coverage
+// is below the threshold.
+func checkCoverageThreshold(report cover.Report, threshold float64) error {
+	if report.Coverage >= threshold {
+		return nil
+	}
+
+	return &CoverageThresholdError{Coverage: report.Coverage, Threshold: threshold}
+}
+
+// writeCoverage writes the coverage report in the given format. When no
+// format is given, the report is written as JSON.
+func writeCoverage(w io.Writer, report cover.Report, format string) error {
+	switch format {
+	case CoverageCobertura:
+		return writeCobertura(w, report)
+	case CoverageLCOV:
+		return writeLCOV(w, report)
+	default:
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal coverage: %w", err)
+		}
+
+		fmt.Fprintln(w, string(out))
+		return nil
+	}
+}
+
+// coverageLine is a line of a policy that can be covered.
+type coverageLine struct {
+	number  int
+	covered bool
+}
+
+// coverageLines returns the lines of the file that can be covered, in order.
+func coverageLines(fileReport *cover.FileReport) []coverageLine {
+	var lines []coverageLine
+	for _, r := range fileReport.Covered {
+		for row := r.Start.Row; row <= r.End.Row; row++ {
+			lines = append(lines, coverageLine{number: row, covered: true})
+		}
+	}
+
+	for _, r := range fileReport.NotCovered {
+		for row := r.Start.Row; row <= r.End.Row; row++ {
+			lines = append(lines, coverageLine{number: row})
+		}
+	}
+
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].number < lines[j].number
+	})
+
+	return lines
+}
+
+func sortedCoverageFiles(report cover.Report) []string {
+	var files []string
+	for file := range report.Files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	return files
+}
+
+// writeLCOV writes the coverage report in the lcov tracefile format. As only
+// whether a line was evaluated is known, the hit count of a line is 0 or 1.
+func writeLCOV(w io.Writer, report cover.Report) error {
+	for _, file := range sortedCoverageFiles(report) {
+		fmt.Fprintln(w, "TN:")
+		fmt.Fprintf(w, "SF:%s\n", file)
+
+		var hit int
+		lines := coverageLines(report.Files[file])
+		for _, line := range lines {
+			hits := 0
+			if line.covered {
+				hits = 1
+				hit++
+			}
+
+			fmt.Fprintf(w, "DA:%d,%d\n", line.number, hits)
+		}
+
+		fmt.Fprintf(w, "LF:%d\n", len(lines))
+		fmt.Fprintf(w, "LH:%d\n", hit)
+		fmt.Fprintln(w, "end_of_record")
+	}
+
+	return nil
+}
+
+type coberturaCoverage struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        float64            `xml:"line-rate,attr"`
+	BranchRate      float64            `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      float64            `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Sources         []string           `xml:"sources>source"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   float64          `xml:"line-rate,attr"`
+	BranchRate float64          `xml:"branch-rate,attr"`
+	Complexity float64          `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string          `xml:"name,attr"`
+	Filename   string          `xml:"filename,attr"`
+	LineRate   float64         `xml:"line-rate,attr"`
+	BranchRate float64         `xml:"branch-rate,attr"`
+	Complexity float64         `xml:"complexity,attr"`
+	Methods    struct{}        `xml:"methods"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number int `xml:"number,attr"`
+	Hits   int `xml:"hits,attr"`
+}
+
+// writeCobertura writes the coverage report in the Cobertura XML format. Each
+// policy is a class, within a package for the directory of the policy.
+func writeCobertura(w io.Writer, report cover.Report) error {
+	coverage := coberturaCoverage{
+		Timestamp: time.Now().Unix(),
+		Sources:   []string{"."},
+	}
+
+	packages := make(map[string]*coberturaPackage)
+	var packageNames []string
+	for _, file := range sortedCoverageFiles(report) {
+		class := coberturaClass{
+			Name:     path.Base(filepath.ToSlash(file)),
+			Filename: filepath.ToSlash(file),
+		}
+
+		var covered int
+		lines := coverageLines(report.Files[file])
+		for _, line := range lines {
+			hits := 0
+			if line.covered {
+				hits = 1
+				covered++
+			}
+
+			class.Lines = append(class.Lines, coberturaLine{Number: line.number, Hits: hits})
+		}
+		class.LineRate = lineRate(covered, len(lines))
+
+		name := path.Dir(filepath.ToSlash(file))
+		pkg, ok := packages[name]
+		if !ok {
+			pkg = &coberturaPackage{Name: name}
+			packages[name] = pkg
+			packageNames = append(packageNames, name)
+		}
+		pkg.Classes = append(pkg.Classes, class)
+
+		coverage.LinesCovered += covered
+		coverage.LinesValid += len(lines)
+	}
+
+	for _, name := range packageNames {
+		pkg := packages[name]
+
+		var covered, total int
+		for _, class := range pkg.Classes {
+			for _, line := range class.Lines {
+				covered += line.Hits
+			}
+			total += len(class.Lines)
+		}
+
+		pkg.LineRate = lineRate(covered, total)
+		coverage.Packages = append(coverage.Packages, *pkg)
+	}
+	coverage.LineRate = lineRate(coverage.LinesCovered, coverage.LinesValid)
+
+	out, err := xml.MarshalIndent(coverage, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal cobertura: %w", err)
+	}
+
+	fmt.Fprint(w, xml.Header)
+	fmt.Fprintln(w, `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">`)
+	fmt.Fprintln(w, string(out))
+
+	return nil
+}
+
+func lineRate(covered int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(covered) / float64(total)
+}
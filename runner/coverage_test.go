@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/open-policy-agent/opa/cover"
+)
+
+func testCoverageReport() cover.Report {
+	return cover.Report{
+		Files: map[string]*cover.FileReport{
+			"policy/deny.rego": {
+				Covered:    []cover.Range{{Start: cover.Position{Row: 3}, End: cover.Position{Row: 4}}},
+				NotCovered: []cover.Range{{Start: cover.Position{Row: 6}, End: cover.Position{Row: 6}}},
+				Coverage:   66.67,
+			},
+		},
+		Coverage: 66.67,
+	}
+}
+
+func TestWriteLCOV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeCoverage(&buf, testCoverageReport(), CoverageLCOV); err != nil {
+		t.Fatalf("write coverage: %v", err)
+	}
+
+	expected := `TN:
+SF:policy/deny.rego
+DA:3,1
+DA:4,1
+DA:6,0
+LF:3
+LH:2
+end_of_record
+`
+	if buf.String() != expected {
+		t.Errorf("expected lcov:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteCobertura(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeCoverage(&buf, testCoverageReport(), CoverageCobertura); err != nil {
+		t.Fatalf("write coverage: %v", err)
+	}
+
+	var coverage coberturaCoverage
+	if err := xml.Unmarshal(buf.Bytes(), &coverage); err != nil {
+		t.Fatalf("unmarshal cobertura: %v", err)
+	}
+
+	if coverage.LinesCovered != 2 || coverage.LinesValid != 3 {
+		t.Errorf("expected 2 of 3 lines to be covered, got %d of %d", coverage.LinesCovered, coverage.LinesValid)
+	}
+
+	if len(coverage.Packages) != 1 || coverage.Packages[0].Name != "policy" {
+		t.Fatalf("expected a single policy package, got %+v", coverage.Packages)
+	}
+
+	class := coverage.Packages[0].Classes[0]
+	if class.Filename != "policy/deny.rego" || len(class.Lines) != 3 || class.Lines[2].Hits != 0 {
+		t.Errorf("unexpected class %+v", class)
+	}
+}
+
+func TestCheckCoverageThreshold(t *testing.T) {
+	if err := checkCoverageThreshold(testCoverageReport(), 60); err != nil {
+		t.Errorf("expected no error when the threshold is met, got %v", err)
+	}
+
+	err := checkCoverageThreshold(testCoverageReport(), 80)
+	var thresholdErr *CoverageThresholdError
+	if !errors.As(err, &thresholdErr) {
+		t.Fatalf("expected a coverage threshold error, got %v", err)
+	}
+
+	expected := "coverage of 66.67% is below the threshold of 80.00%"
+	if err.Error() != expected {
+		t.Errorf("expected error %q, got %q", expected, err.Error())
+	}
+}
+
+func TestVerifyRunnerCoverageThreshold(t *testing.T) {
+	runner := VerifyRunner{
+		Policy:            []string{"../examples/kubernetes/policy"},
+		CoverageThreshold: 90,
+	}
+
+	results, err := runner.Run(context.Background())
+	var thresholdErr *CoverageThresholdError
+	if !errors.As(err, &thresholdErr) {
+		t.Fatalf("expected a coverage threshold error, got %v", err)
+	}
+
+	// The coverage is not part of the results, only the tests are.
+	for _, result := range results {
+		if len(result.Failures) > 0 {
+			t.Errorf("expected the tests to pass, got failures %+v", result.Failures)
+		}
+	}
+
+	if exitCode := runner.ExitCode(results); exitCode != 0 {
+		t.Errorf("expected exit code 0 for the results, got %d", exitCode)
+	}
+}
+
+func TestVerifyRunnerCoverageStderr(t *testing.T) {
+	var stderr bytes.Buffer
+	runner := VerifyRunner{
+		Policy:   []string{"../examples/kubernetes/policy"},
+		Coverage: true,
+		Stderr:   &stderr,
+	}
+
+	if _, err := runner.Run(context.Background()); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	var report cover.Report
+	if err := json.Unmarshal(stderr.Bytes(), &report); err != nil {
+		t.Fatalf("expected the coverage report to be written to the runner's stderr: %v", err)
+	}
+
+	if len(report.Files) == 0 {
+		t.Errorf("expected the coverage of the policies, got %+v", report)
+	}
+}
@@ -0,0 +1,38 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/open-policy-agent/conftest/output"
+)
+
+// ExitError is returned by the commands instead of exiting, when the
+// results of a run require a non-zero exit code.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the exit code of the results of a test run, taking
+// the no-fail and fail-on-warn parameters of the runner into account.
+func (t *TestRunner) ExitCode(results []output.CheckResult) int {
+	// When the no-fail parameter is set, there is no need to figure out the error code
+	// as we always want to return zero.
+	if t.NoFail {
+		return 0
+	}
+
+	if t.FailOnWarn {
+		return output.ExitCodeFailOnWarn(results)
+	}
+
+	return output.ExitCode(results)
+}
+
+// ExitCode returns the exit code of the results of a verification run.
+func (r *VerifyRunner) ExitCode(results []output.CheckResult) int {
+	return output.ExitCode(results)
+}
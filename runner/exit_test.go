@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-policy-agent/conftest/output"
+)
+
+func TestTestRunnerExitCode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		runner   TestRunner
+		expected int
+	}{
+		{name: "default", runner: TestRunner{}, expected: 1},
+		{name: "no fail", runner: TestRunner{NoFail: true}, expected: 0},
+		{name: "fail on warn", runner: TestRunner{FailOnWarn: true}, expected: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.runner.Policy = []string{"../examples/kubernetes/policy"}
+			tc.runner.Namespace = []string{"main"}
+
+			results, err := tc.runner.Run(context.Background(), []string{"../examples/kubernetes/deployment.yaml"})
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+
+			if exitCode := tc.runner.ExitCode(results); exitCode != tc.expected {
+				t.Errorf("expected exit code %d, got %d", tc.expected, exitCode)
+			}
+		})
+	}
+}
+
+func TestVerifyRunnerExitCode(t *testing.T) {
+	runner := VerifyRunner{Policy: []string{"../examples/kubernetes/policy"}}
+
+	results, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if exitCode := runner.ExitCode(results); exitCode != 0 {
+		t.Errorf("expected exit code 0, got %d", exitCode)
+	}
+
+	failed := []output.CheckResult{{Failures: []output.Result{{Message: "test_fails"}}}}
+	if exitCode := runner.ExitCode(failed); exitCode != 1 {
+		t.Errorf("expected exit code 1, got %d", exitCode)
+	}
+}
@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-policy-agent/conftest/internal/gitignore"
+	ignore "github.com/shteou/go-ignore"
+)
+
+const (
+	// conftestIgnoreFile lists the paths that are ignored when testing a directory.
+	conftestIgnoreFile = ".conftestignore"
+
+	// gitIgnoreFile lists the paths that are ignored by git. It is only
+	// used when the .gitignore files are explicitly enabled.
+	gitIgnoreFile = ".gitignore"
+)
+
+// ignoreMatcher determines which paths are ignored based on the ignore
+// files found while walking a directory. Similar to git, the ignore files
+// in a directory apply to everything inside of that directory, and the
+// last pattern that matches a path decides whether it is ignored.
+type ignoreMatcher struct {
+	fileNames []string
+	patterns  []gitignore.Pattern
+}
+
+func newIgnoreMatcher(useGitignore bool) *ignoreMatcher {
+	fileNames := []string{conftestIgnoreFile}
+	if useGitignore {
+		fileNames = []string{gitIgnoreFile, conftestIgnoreFile}
+	}
+
+	return &ignoreMatcher{fileNames: fileNames}
+}
+
+// load adds the patterns of the ignore files in the given directory.
+// The patterns of .conftestignore are added last, so that they take
+// precedence over the patterns of .gitignore.
+func (m *ignoreMatcher) load(root string, dir string) error {
+	base, err := relativePath(root, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, fileName := range m.fileNames {
+		contents, err := ioutil.ReadFile(filepath.Join(dir, fileName))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("read %s: %w", fileName, err)
+		}
+
+		entries, err := ignore.ParseIgnoreBytes(contents)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", fileName, err)
+		}
+
+		for _, entry := range entries {
+			if entry.Kind != "Path" && entry.Kind != "NegatedPath" {
+				continue
+			}
+
+			p, err := gitignore.Compile(base, strings.TrimSuffix(entry.Value, "\r"))
+			if err != nil {
+				return fmt.Errorf("compile pattern %q in %s: %w", entry.Original, filepath.Join(dir, fileName), err)
+			}
+
+			p.Negated = entry.Kind == "NegatedPath"
+			m.patterns = append(m.patterns, p)
+		}
+	}
+
+	return nil
+}
+
+// ignored reports whether the given path, which uses forward slashes
+// and is relative to the root of the walk, is ignored.
+func (m *ignoreMatcher) ignored(relPath string, isDir bool) bool {
+	var ignored bool
+	for _, p := range m.patterns {
+		if p.Match(relPath, isDir) {
+			ignored = !p.Negated
+		}
+	}
+
+	return ignored
+}
+
+// includeMatcher determines which files are included based on the
+// patterns given with the include flag. When no patterns are given,
+// all of the files are included.
+type includeMatcher struct {
+	patterns []gitignore.Pattern
+}
+
+func newIncludeMatcher(includes []string) (*includeMatcher, error) {
+	var m includeMatcher
+	for _, include := range includes {
+		p, err := gitignore.Compile("", include)
+		if err != nil {
+			return nil, fmt.Errorf("compile include pattern %q: %w", include, err)
+		}
+
+		m.patterns = append(m.patterns, p)
+	}
+
+	return &m, nil
+}
+
+func (m *includeMatcher) included(relPath string) bool {
+	if len(m.patterns) == 0 {
+		return true
+	}
+
+	for _, p := range m.patterns {
+		if p.Match(relPath, false) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// relativePath returns the path relative to the root using forward
+// slashes. The root itself is returned as an empty path.
+func relativePath(root string, currentPath string) (string, error) {
+	relPath, err := filepath.Rel(root, currentPath)
+	if err != nil {
+		return "", fmt.Errorf("relative path: %w", err)
+	}
+
+	relPath = filepath.ToSlash(relPath)
+	if relPath == "." {
+		return "", nil
+	}
+
+	return path.Clean(relPath), nil
+}
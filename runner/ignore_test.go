@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestGetFilesFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		".conftestignore":          "node_modules/\n*.json\n!keep.json\n",
+		".gitignore":               "generated/\n",
+		"service.yaml":             "kind: Service",
+		"data.json":                "{}",
+		"keep.json":                "{}",
+		"node_modules/x/pkg.json":  "{}",
+		"generated/out.yaml":       "kind: Service",
+		"nested/.conftestignore":   "local.yaml\n",
+		"nested/local.yaml":        "kind: Service",
+		"nested/deployment.yaml":   "kind: Deployment",
+		"other/local.yaml":         "kind: Service",
+		"other/unsupported.config": "",
+		"other/service.config":     `{"kind": "Service"}`,
+	}
+
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		filter   fileFilter
+		expected []string
+	}{
+		{
+			name: "conftestignore",
+			expected: []string{
+				".gitignore",
+				"generated/out.yaml",
+				"keep.json",
+				"nested/deployment.yaml",
+				"other/local.yaml",
+				"service.yaml",
+			},
+		},
+		{
+			name:   "gitignore",
+			filter: fileFilter{gitignore: true},
+			expected: []string{
+				".gitignore",
+				"keep.json",
+				"nested/deployment.yaml",
+				"other/local.yaml",
+				"service.yaml",
+			},
+		},
+		{
+			name:   "include",
+			filter: fileFilter{includes: []string{"*.yaml"}},
+			expected: []string{
+				"generated/out.yaml",
+				"nested/deployment.yaml",
+				"other/local.yaml",
+				"service.yaml",
+			},
+		},
+		{
+			name:   "detect format",
+			filter: fileFilter{detectFormat: true},
+			expected: []string{
+				".gitignore",
+				"generated/out.yaml",
+				"keep.json",
+				"nested/deployment.yaml",
+				"other/local.yaml",
+				"other/service.config",
+				"service.yaml",
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := getFilesFromDirectory(dir, tt.filter)
+			if err != nil {
+				t.Fatalf("get files from directory: %v", err)
+			}
+
+			var relPaths []string
+			for _, file := range actual {
+				relPath, err := relativePath(dir, file)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				relPaths = append(relPaths, relPath)
+			}
+			sort.Strings(relPaths)
+
+			if !reflect.DeepEqual(relPaths, tt.expected) {
+				t.Errorf("expected files %v, got %v", tt.expected, relPaths)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/conftest/parser"
+)
+
+// loadParams loads the parameters that are passed to the policies. The
+// parameter files are merged in order, and the parameters given as KEY=VALUE
+// pairs are applied last, so they take precedence over the files.
+//
+// Values that are valid JSON, such as numbers, booleans or lists, keep
+// their type. Any other value is used as a string.
+func loadParams(paramFiles []string, params []string) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, paramFile := range paramFiles {
+		configurations, err := parser.ParseConfigurations([]string{paramFile})
+		if err != nil {
+			return nil, fmt.Errorf("parse param file: %w", err)
+		}
+
+		fileValues, ok := configurations[paramFile].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("param file %s must contain an object", paramFile)
+		}
+
+		for key, value := range fileValues {
+			values[key] = value
+		}
+	}
+
+	for _, param := range params {
+		separator := strings.Index(param, "=")
+		if separator < 1 {
+			return nil, fmt.Errorf("invalid param %q: must be in the form KEY=VALUE", param)
+		}
+
+		key, rawValue := param[:separator], param[separator+1:]
+
+		var value interface{}
+		if err := json.Unmarshal([]byte(rawValue), &value); err != nil {
+			value = rawValue
+		}
+
+		values[key] = value
+	}
+
+	return values, nil
+}
@@ -0,0 +1,41 @@
+package runner
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadParams(t *testing.T) {
+	dir := t.TempDir()
+	paramFile := filepath.Join(dir, "params.yaml")
+	if err := ioutil.WriteFile(paramFile, []byte("env: prod\nmax_replicas: 10\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	params, err := loadParams([]string{paramFile}, []string{"max_replicas=3", "debug=true", "owner=team=platform", "regions=[\"eu-west-1\"]"})
+	if err != nil {
+		t.Fatalf("load params: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"env":          "prod",
+		"max_replicas": 3.0,
+		"debug":        true,
+		"owner":        "team=platform",
+		"regions":      []interface{}{"eu-west-1"},
+	}
+
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("expected params %v, got %v", expected, params)
+	}
+}
+
+func TestLoadParams_Invalid(t *testing.T) {
+	for _, param := range []string{"env", "=prod"} {
+		if _, err := loadParams(nil, []string{param}); err == nil {
+			t.Errorf("expected an error for param %q", param)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/open-policy-agent/conftest/policy"
+)
+
+// The formats of the profile of a test run.
+const (
+	ProfileTable = "table"
+	ProfileJSON  = "json"
+)
+
+func validateProfileFormat(format string) error {
+	switch format {
+	case "", ProfileTable, ProfileJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown profile format %q, valid formats are: %s, %s", format, ProfileTable, ProfileJSON)
+	}
+}
+
+// writeProfile writes the profile in the given format. When no format is
+// given, the profile is written as tables.
+func writeProfile(w io.Writer, profile policy.Profile, format string) error {
+	if format == ProfileJSON {
+		out, err := json.MarshalIndent(profile, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal profile: %w", err)
+		}
+
+		fmt.Fprintln(w, string(out))
+		return nil
+	}
+
+	var expressions [][]string
+	for _, expression := range profile.Expressions {
+		expressions = append(expressions, []string{expression.Time.String(), strconv.Itoa(expression.NumEval), strconv.Itoa(expression.NumRedo), expression.Location, expression.Expression})
+	}
+	writeProfileTable(w, "Slowest expressions", []string{"time", "num eval", "num redo", "location", "expression"}, expressions)
+
+	var rules [][]string
+	for _, rule := range profile.Rules {
+		rules = append(rules, []string{rule.Time.String(), strconv.Itoa(rule.NumEval), rule.Location, rule.Namespace, rule.Rule})
+	}
+	writeProfileTable(w, "Slowest rules", []string{"time", "num eval", "location", "namespace", "rule"}, rules)
+
+	var evaluations [][]string
+	for _, evaluation := range profile.Evaluations {
+		evaluations = append(evaluations, []string{evaluation.Time.String(), evaluation.File, evaluation.Namespace, evaluation.Rule})
+	}
+	writeProfileTable(w, "Slowest evaluations", []string{"time", "file", "namespace", "rule"}, evaluations)
+
+	return nil
+}
+
+func writeProfileTable(w io.Writer, title string, header []string, data [][]string) {
+	fmt.Fprintln(w, title)
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	table.SetAutoWrapText(false)
+	table.AppendBulk(data)
+	table.Render()
+
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,158 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Rule selects the parser and the namespaces that are used for the files
+// that match any of its patterns. Rules are set in the configuration file:
+//
+//	[[rules]]
+//	files = ["k8s/**/*.yaml"]
+//	namespaces = ["kubernetes"]
+//	parser = "yaml"
+type Rule struct {
+
+	// Files are gitignore-style patterns that are matched against the
+	// paths of the files, relative to the working directory.
+	Files []string
+
+	// Namespaces are the namespaces to test the matching files against.
+	// When empty, the namespaces of the namespace flag are used.
+	Namespaces []string
+
+	// Parser is the parser to use for the matching files. When empty,
+	// the parser of the parser flag is used, if any.
+	Parser string
+}
+
+// fileGroup is a group of files that are parsed with the same parser and
+// tested against the same namespaces.
+type fileGroup struct {
+	files      []string
+	parser     string
+	namespaces []string
+}
+
+// ruleMatcher finds the first rule whose patterns match a file.
+type ruleMatcher struct {
+	matchers         []*includeMatcher
+	workingDirectory string
+}
+
+func newRuleMatcher(rules []Rule) (*ruleMatcher, error) {
+	matchers := make([]*includeMatcher, len(rules))
+	for r, rule := range rules {
+		if len(rule.Files) == 0 {
+			return nil, fmt.Errorf("rule %d: no file patterns", r+1)
+		}
+
+		matcher, err := newIncludeMatcher(rule.Files)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", r+1, err)
+		}
+
+		matchers[r] = matcher
+	}
+
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("get working directory: %w", err)
+	}
+
+	return &ruleMatcher{matchers: matchers, workingDirectory: workingDirectory}, nil
+}
+
+// match returns the index of the first rule that matches the file or
+// directory, or the number of rules when no rule matches it. Standard
+// input never matches a rule.
+func (m *ruleMatcher) match(file string) (int, error) {
+	if file == "-" {
+		return len(m.matchers), nil
+	}
+
+	relPath, err := ruleRelativePath(m.workingDirectory, file)
+	if err != nil {
+		return 0, err
+	}
+
+	for r, matcher := range m.matchers {
+		if matcher.included(relPath) {
+			return r, nil
+		}
+	}
+
+	return len(m.matchers), nil
+}
+
+// ruleParser returns the parser of the first rule that matches the file or
+// directory, or the default parser when no rule matches it or the rule does
+// not set a parser.
+func ruleParser(file string, rules []Rule, defaultParser string) (string, error) {
+	matcher, err := newRuleMatcher(rules)
+	if err != nil {
+		return "", err
+	}
+
+	r, err := matcher.match(file)
+	if err != nil {
+		return "", err
+	}
+
+	if r < len(rules) && rules[r].Parser != "" {
+		return rules[r].Parser, nil
+	}
+
+	return defaultParser, nil
+}
+
+// groupFiles groups the files by the first rule that matches them. Files
+// that do not match any rule are grouped together using the given defaults.
+// The groups are returned in the order of the rules, followed by the group
+// of files that do not match any rule.
+func groupFiles(files []string, rules []Rule, defaultParser string) ([]fileGroup, error) {
+	matcher, err := newRuleMatcher(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]fileGroup, len(rules)+1)
+	for r, rule := range rules {
+		groups[r] = fileGroup{parser: rule.Parser, namespaces: rule.Namespaces}
+		if rule.Parser == "" {
+			groups[r].parser = defaultParser
+		}
+	}
+	groups[len(rules)] = fileGroup{parser: defaultParser}
+
+	for _, file := range files {
+		index, err := matcher.match(file)
+		if err != nil {
+			return nil, err
+		}
+
+		groups[index].files = append(groups[index].files, file)
+	}
+
+	var nonEmptyGroups []fileGroup
+	for _, group := range groups {
+		if len(group.files) > 0 {
+			nonEmptyGroups = append(nonEmptyGroups, group)
+		}
+	}
+
+	return nonEmptyGroups, nil
+}
+
+// ruleRelativePath returns the path of the file relative to the working
+// directory, which is where the configuration file is read from.
+func ruleRelativePath(workingDirectory string, file string) (string, error) {
+	absPath, err := filepath.Abs(file)
+	if err != nil {
+		return "", fmt.Errorf("get abs: %w", err)
+	}
+
+	return relativePath(workingDirectory, absPath)
+}
@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGroupFiles(t *testing.T) {
+	rules := []Rule{
+		{Files: []string{"k8s/**/*.yaml"}, Namespaces: []string{"kubernetes"}},
+		{Files: []string{"Dockerfile"}, Namespaces: []string{"docker"}, Parser: "dockerfile"},
+		{Files: []string{"k8s/prod/*.yaml"}, Namespaces: []string{"unused"}},
+	}
+
+	files := []string{"k8s/service.yaml", "k8s/prod/deployment.yaml", "build/Dockerfile", "main.tf", "-"}
+
+	actual, err := groupFiles(files, rules, "hcl2")
+	if err != nil {
+		t.Fatalf("group files: %v", err)
+	}
+
+	expected := []fileGroup{
+		{files: []string{"k8s/service.yaml", "k8s/prod/deployment.yaml"}, parser: "hcl2", namespaces: []string{"kubernetes"}},
+		{files: []string{"build/Dockerfile"}, parser: "dockerfile", namespaces: []string{"docker"}},
+		{files: []string{"main.tf", "-"}, parser: "hcl2"},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected groups %v, got %v", expected, actual)
+	}
+
+	if _, err := groupFiles(files, []Rule{{Namespaces: []string{"kubernetes"}}}, ""); err == nil {
+		t.Error("expected an error for a rule without file patterns")
+	}
+}
+
+func TestParseFileListTerraformRule(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"infra/main.tf", "infra/variables.tf", "k8s/service.yaml"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The patterns of the rules are relative to the working directory.
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(workingDirectory) })
+
+	runner := TestRunner{Rules: []Rule{{Files: []string{"infra"}, Parser: "terraform"}}}
+
+	files, err := parseFileList([]string{"infra", "k8s"}, fileFilter{}, runner.keepDirectory)
+	if err != nil {
+		t.Fatalf("parse file list: %v", err)
+	}
+
+	expected := []string{"infra", filepath.Join("k8s", "service.yaml")}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected files %v, got %v", expected, files)
+	}
+
+	groups, err := groupFiles(files, runner.Rules, runner.Parser)
+	if err != nil {
+		t.Fatalf("group files: %v", err)
+	}
+
+	if len(groups) != 2 || groups[0].parser != "terraform" || !reflect.DeepEqual(groups[0].files, []string{"infra"}) {
+		t.Errorf("expected the directory to be parsed with the terraform parser, got %v", groups)
+	}
+}
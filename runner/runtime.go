@@ -0,0 +1,34 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/open-policy-agent/conftest/policy"
+)
+
+// stderr returns the writer that the runner writes warnings and reports to,
+// or os.Stderr when the runner does not set one.
+func stderr(w io.Writer) io.Writer {
+	if w == nil {
+		return os.Stderr
+	}
+
+	return w
+}
+
+// setRuntimeEnv exposes the environment variables that match the patterns to
+// the policies, and writes a warning to w about the policies that read any
+// other variable.
+func setRuntimeEnv(w io.Writer, engine *policy.Engine, patterns []string) error {
+	if err := engine.SetRuntimeEnv(patterns); err != nil {
+		return err
+	}
+
+	for _, read := range engine.BlockedEnvReads() {
+		fmt.Fprintf(w, "WARN: %s reads the environment variable %s through opa.runtime(), but it is not exposed to policies. Use --runtime-env to expose it.\n", read.Policy, read.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,418 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/open-policy-agent/conftest/downloader"
+	"github.com/open-policy-agent/conftest/output"
+	"github.com/open-policy-agent/conftest/parser"
+	"github.com/open-policy-agent/conftest/parser/docker"
+	"github.com/open-policy-agent/conftest/policy"
+)
+
+// TestRunner is the runner for the Test command, executing
+// Rego policy checks against configuration files.
+type TestRunner struct {
+	Trace              bool
+	Policy             []string
+	Data               []string
+	DataPrefix         bool `mapstructure:"data-prefix"`
+	DataNested         bool `mapstructure:"data-nested"`
+	Update             []string
+	Params             []string `mapstructure:"param"`
+	ParamFiles         []string `mapstructure:"param-file"`
+	RuntimeEnv         []string `mapstructure:"runtime-env"`
+	Capabilities       string
+	StrictBuiltins     bool `mapstructure:"strict-builtins"`
+	Ignore             string
+	Include            []string
+	GitIgnore          bool `mapstructure:"gitignore"`
+	Parser             string
+	ExpandArgs         bool     `mapstructure:"expand-args"`
+	BuildArgs          []string `mapstructure:"build-arg"`
+	FollowModules      bool     `mapstructure:"follow-modules"`
+	HCL2Ranges         bool     `mapstructure:"hcl2-ranges"`
+	DetectFormat       bool     `mapstructure:"detect-format"`
+	ContinueOnError    bool     `mapstructure:"continue-on-error"`
+	Namespace          []string
+	AllNamespaces      bool `mapstructure:"all-namespaces"`
+	FailOnWarn         bool `mapstructure:"fail-on-warn"`
+	NoColor            bool `mapstructure:"no-color"`
+	NoFail             bool `mapstructure:"no-fail"`
+	SuppressExceptions bool `mapstructure:"suppress-exceptions"`
+	Combine            bool
+	Output             string
+	Timeout            time.Duration
+	QueryTimeout       time.Duration `mapstructure:"query-timeout"`
+	Profile            bool
+	ProfileFormat      string `mapstructure:"profile-format"`
+	ProfileLimit       int    `mapstructure:"profile-limit"`
+	Coverage           bool
+	CoverageFormat     string  `mapstructure:"coverage-format"`
+	CoverageFile       string  `mapstructure:"coverage-file"`
+	CoverageThreshold  float64 `mapstructure:"coverage-threshold"`
+	Rules              []Rule
+
+	// Stderr is where the warnings about the policies, the profile and the
+	// coverage report are written to, so that they do not interfere with
+	// the output of the results. When nil, os.Stderr is used.
+	Stderr io.Writer `mapstructure:"-"`
+}
+
+// Run executes the TestRunner, verifying all Rego policies against the given
+// list of configuration files. When the timeout of the runner is exceeded, or
+// the context is cancelled, no results are returned. When the coverage is below
+// the coverage threshold, the results are returned with a *CoverageThresholdError.
+func (t *TestRunner) Run(ctx context.Context, fileList []string) ([]output.CheckResult, error) {
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+
+	results, err := t.run(ctx, fileList)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("test run timed out after %s", t.Timeout)
+	} else if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		return nil, fmt.Errorf("test run was cancelled")
+	}
+
+	return results, err
+}
+
+func (t *TestRunner) run(ctx context.Context, fileList []string) ([]output.CheckResult, error) {
+	if err := validateProfileFormat(t.ProfileFormat); err != nil {
+		return nil, err
+	}
+
+	if err := validateCoverageFormat(t.CoverageFormat); err != nil {
+		return nil, err
+	}
+
+	filter := fileFilter{
+		ignoreRegex:  t.Ignore,
+		includes:     t.Include,
+		gitignore:    t.GitIgnore,
+		detectFormat: t.DetectFormat,
+	}
+
+	files, err := parseFileList(fileList, filter, t.keepDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("parse files: %w", err)
+	}
+
+	groups, err := groupFiles(files, t.Rules, t.Parser)
+	if err != nil {
+		return nil, fmt.Errorf("rules: %w", err)
+	}
+
+	// Files that fail to parse are reported as errors in the results,
+	// while the remaining files are still evaluated.
+	var results []output.CheckResult
+	configurations := make([]map[string]interface{}, len(groups))
+	parsers := make(map[string]string)
+	for g, group := range groups {
+		options := parser.Options{
+			Parser:          group.parser,
+			ExpandArgs:      t.ExpandArgs,
+			BuildArgs:       docker.ParseBuildArgs(t.BuildArgs),
+			FollowModules:   t.FollowModules,
+			Ranges:          t.HCL2Ranges,
+			DetectFormat:    t.DetectFormat,
+			ContinueOnError: t.ContinueOnError,
+		}
+
+		var groupParsers map[string]string
+		configurations[g], groupParsers, err = parser.ParseConfigurationsWithParsers(group.files, options)
+		for path, name := range groupParsers {
+			parsers[path] = name
+		}
+
+		var parseErrors parser.ParseErrors
+		if errors.As(err, &parseErrors) {
+			for _, parseError := range parseErrors {
+				results = append(results, output.CheckResult{
+					FileName:  parseError.Path,
+					Namespace: "-",
+					Errors:    []output.Result{{Message: parseError.Err.Error()}},
+				})
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("parse configurations: %w", err)
+		}
+	}
+
+	// When there are policies to download, they are currently placed in the first
+	// directory that appears in the list of policies.
+	if len(t.Update) > 0 {
+		if err := downloader.Download(ctx, t.Policy[0], t.Update); err != nil {
+			return nil, fmt.Errorf("update policies: %w", err)
+		}
+	}
+
+	dataPaths := t.Data
+	if t.DataPrefix {
+		dataPaths, err = policy.PrefixDataPaths(t.Data)
+		if err != nil {
+			return nil, fmt.Errorf("prefix data paths: %w", err)
+		}
+	}
+
+	capabilities, err := policy.LoadCapabilities(t.Capabilities, t.StrictBuiltins)
+	if err != nil {
+		return nil, fmt.Errorf("load capabilities: %w", err)
+	}
+
+	options := []policy.Option{
+		policy.WithPolicies(t.Policy...),
+		policy.WithData(dataPaths...),
+		policy.WithCapabilities(capabilities),
+		policy.WithQueryTimeout(t.QueryTimeout),
+	}
+	if t.DataNested {
+		options = append(options, policy.WithNestedData())
+	}
+	if t.Trace {
+		options = append(options, policy.WithTracing())
+	}
+	if t.Profile {
+		options = append(options, policy.WithProfiling())
+	}
+	if t.Coverage || t.CoverageThreshold > 0 {
+		options = append(options, policy.WithCoverage())
+	}
+
+	engine, err := policy.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("load: %w", err)
+	}
+
+	engine.SetParsers(parsers)
+
+	if err := engine.SetRoots(fileList); err != nil {
+		return nil, fmt.Errorf("set roots: %w", err)
+	}
+
+	if err := setRuntimeEnv(stderr(t.Stderr), engine, t.RuntimeEnv); err != nil {
+		return nil, fmt.Errorf("set runtime env: %w", err)
+	}
+
+	params, err := loadParams(t.ParamFiles, t.Params)
+	if err != nil {
+		return nil, fmt.Errorf("load params: %w", err)
+	}
+
+	if err := engine.SetParams(ctx, params); err != nil {
+		return nil, fmt.Errorf("set params: %w", err)
+	}
+
+	defaultNamespaces := t.Namespace
+	if t.AllNamespaces {
+		defaultNamespaces = engine.Namespaces()
+	}
+
+	for g, group := range groups {
+		namespaces := group.namespaces
+		if len(namespaces) == 0 {
+			namespaces = defaultNamespaces
+		}
+
+		for _, namespace := range namespaces {
+			if t.Combine {
+				result, err := engine.CheckCombined(ctx, configurations[g], namespace)
+				if err != nil {
+					return nil, fmt.Errorf("check combined: %w", err)
+				}
+
+				results = append(results, result)
+			} else {
+				result, err := engine.Check(ctx, configurations[g], namespace)
+				if err != nil {
+					return nil, fmt.Errorf("query rule: %w", err)
+				}
+
+				results = append(results, result...)
+			}
+		}
+	}
+
+	// The profile is only written once all of the policies have been
+	// evaluated, so that it is aggregated across all of the files.
+	if t.Profile {
+		if err := writeProfile(stderr(t.Stderr), engine.Profile(t.ProfileLimit), t.ProfileFormat); err != nil {
+			return nil, fmt.Errorf("write profile: %w", err)
+		}
+	}
+
+	coverage := engine.Coverage()
+	if t.Coverage {
+		if err := writeCoverageReport(stderr(t.Stderr), coverage, t.CoverageFormat, t.CoverageFile); err != nil {
+			return nil, fmt.Errorf("write coverage: %w", err)
+		}
+	}
+
+	// The results are returned even when the coverage is below the
+	// threshold, so that they can still be written before failing.
+	return results, checkCoverageThreshold(coverage, t.CoverageThreshold)
+}
+
+// keepDirectory reports whether the directory is tested as a whole. Terraform
+// modules are made up of all of the files in a directory, so directories are
+// parsed as a whole instead of file by file when they are parsed with the
+// terraform parser, either because of the parser flag or because of the rule
+// that matches the directory.
+func (t *TestRunner) keepDirectory(directory string) (bool, error) {
+	name, err := ruleParser(directory, t.Rules, t.Parser)
+	if err != nil {
+		return false, err
+	}
+
+	return name == parser.Terraform, nil
+}
+
+// fileFilter determines which of the files found in a directory are tested.
+type fileFilter struct {
+
+	// ignoreRegex ignores the files whose path matches the regular expression.
+	ignoreRegex string
+
+	// includes are gitignore-style patterns of the files to include.
+	// When empty, all of the files are included.
+	includes []string
+
+	// gitignore ignores the paths listed in .gitignore files, in
+	// addition to the paths listed in .conftestignore files.
+	gitignore bool
+
+	// detectFormat includes the files whose format can be detected from
+	// their contents, in addition to the files with a known extension.
+	detectFormat bool
+}
+
+// parseFileList returns the files to test. Directories are replaced by the
+// files found in them, unless keepDirectory reports that they are kept.
+func parseFileList(fileList []string, filter fileFilter, keepDirectory func(string) (bool, error)) ([]string, error) {
+	var files []string
+	for _, file := range fileList {
+		if file == "" {
+			continue
+		}
+
+		if file == "-" {
+			files = append(files, "-")
+			continue
+		}
+
+		fileInfo, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("get file info: %w", err)
+		}
+
+		if !fileInfo.IsDir() {
+			files = append(files, file)
+			continue
+		}
+
+		keep, err := keepDirectory(file)
+		if err != nil {
+			return nil, fmt.Errorf("rules: %w", err)
+		}
+
+		if keep {
+			files = append(files, file)
+			continue
+		}
+
+		directoryFiles, err := getFilesFromDirectory(file, filter)
+		if err != nil {
+			return nil, fmt.Errorf("get files from directory: %w", err)
+		}
+
+		files = append(files, directoryFiles...)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files found")
+	}
+
+	return files, nil
+}
+
+// getFilesFromDirectory returns the files in the directory that can be parsed.
+// Paths listed in .conftestignore files are ignored, and ignored directories
+// are not walked at all.
+func getFilesFromDirectory(directory string, filter fileFilter) ([]string, error) {
+	regexp, err := regexp.Compile(filter.ignoreRegex)
+	if err != nil {
+		return nil, fmt.Errorf("given regexp couldn't be parsed :%w", err)
+	}
+
+	includes, err := newIncludeMatcher(filter.includes)
+	if err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+
+	ignores := newIgnoreMatcher(filter.gitignore)
+
+	var files []string
+	walk := func(currentPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("walk path: %w", err)
+		}
+
+		relPath, err := relativePath(directory, currentPath)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if relPath != "" && ignores.ignored(relPath, true) {
+				return filepath.SkipDir
+			}
+
+			// Similar to git, the .git directory is always ignored
+			// when the .gitignore files are used.
+			if filter.gitignore && info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+
+			if err := ignores.load(directory, currentPath); err != nil {
+				return fmt.Errorf("load ignore files: %w", err)
+			}
+
+			return nil
+		}
+
+		if ignores.ignored(relPath, false) {
+			return nil
+		}
+
+		if filter.ignoreRegex != "" && regexp.MatchString(currentPath) {
+			return nil
+		}
+
+		if !includes.included(relPath) {
+			return nil
+		}
+
+		if parser.FileSupported(currentPath) || filter.detectFormat && parser.FileDetected(currentPath) {
+			files = append(files, currentPath)
+		}
+
+		return nil
+	}
+
+	err = filepath.Walk(directory, walk)
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
@@ -0,0 +1,162 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/open-policy-agent/conftest/output"
+	"github.com/open-policy-agent/conftest/policy"
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+// VerifyRunner is the runner for the Verify command, executing
+// Rego policy unit-tests.
+type VerifyRunner struct {
+	Policy            []string
+	Data              []string
+	DataPrefix        bool     `mapstructure:"data-prefix"`
+	DataNested        bool     `mapstructure:"data-nested"`
+	RuntimeEnv        []string `mapstructure:"runtime-env"`
+	Capabilities      string
+	StrictBuiltins    bool `mapstructure:"strict-builtins"`
+	Output            string
+	NoColor           bool `mapstructure:"no-color"`
+	Trace             bool
+	Timeout           time.Duration
+	Coverage          bool
+	CoverageFormat    string  `mapstructure:"coverage-format"`
+	CoverageFile      string  `mapstructure:"coverage-file"`
+	CoverageThreshold float64 `mapstructure:"coverage-threshold"`
+
+	// Stderr is where the warnings about the policies and the coverage
+	// report are written to, so that they do not interfere with the
+	// results of the tests. When nil, os.Stderr is used.
+	Stderr io.Writer `mapstructure:"-"`
+}
+
+// Run executes the Rego tests for the given policies. When the timeout of the
+// runner is exceeded, or the context is cancelled, no results are returned.
+// When the coverage is below the coverage threshold, the results are returned
+// with a *CoverageThresholdError.
+func (r *VerifyRunner) Run(ctx context.Context) ([]output.CheckResult, error) {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	results, err := r.run(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("verification timed out after %s", r.Timeout)
+	} else if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		return nil, fmt.Errorf("verification was cancelled")
+	}
+
+	return results, err
+}
+
+func (r *VerifyRunner) run(ctx context.Context) ([]output.CheckResult, error) {
+	if err := validateCoverageFormat(r.CoverageFormat); err != nil {
+		return nil, err
+	}
+
+	dataPaths := r.Data
+	if r.DataPrefix {
+		var err error
+		dataPaths, err = policy.PrefixDataPaths(r.Data)
+		if err != nil {
+			return nil, fmt.Errorf("prefix data paths: %w", err)
+		}
+	}
+
+	capabilities, err := policy.LoadCapabilities(r.Capabilities, r.StrictBuiltins)
+	if err != nil {
+		return nil, fmt.Errorf("load capabilities: %w", err)
+	}
+
+	options := []policy.Option{
+		policy.WithPolicies(r.Policy...),
+		policy.WithData(dataPaths...),
+		policy.WithCapabilities(capabilities),
+	}
+	if r.DataNested {
+		options = append(options, policy.WithNestedData())
+	}
+	if r.Trace {
+		options = append(options, policy.WithTracing())
+	}
+	if r.Coverage || r.CoverageThreshold > 0 {
+		options = append(options, policy.WithCoverage())
+	}
+
+	engine, err := policy.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("load: %w", err)
+	}
+
+	if err := setRuntimeEnv(stderr(r.Stderr), engine, r.RuntimeEnv); err != nil {
+		return nil, fmt.Errorf("set runtime env: %w", err)
+	}
+
+	testResults, err := engine.RunTests(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("running tests: %w", err)
+	}
+
+	var results []output.CheckResult
+	for _, result := range testResults {
+		if result.Error != nil {
+			return nil, fmt.Errorf("run test: %w", result.Error)
+		}
+
+		buf := new(bytes.Buffer)
+		topdown.PrettyTrace(buf, result.Trace)
+		var traces []string
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if len(line) > 0 {
+				traces = append(traces, line)
+			}
+		}
+
+		var outputResult output.Result
+		if result.Fail || result.Skip {
+			outputResult.Message = result.Package + "." + result.Name
+		}
+
+		queryResult := output.QueryResult{
+			Query:   result.Name,
+			Results: []output.Result{outputResult},
+			Traces:  traces,
+		}
+
+		checkResult := output.CheckResult{
+			FileName: result.Location.File,
+			Queries:  []output.QueryResult{queryResult},
+		}
+		if result.Fail {
+			checkResult.Failures = []output.Result{outputResult}
+		} else if result.Skip {
+			checkResult.Skipped = []output.Result{outputResult}
+		} else {
+			checkResult.Successes++
+		}
+
+		results = append(results, checkResult)
+	}
+
+	coverage := engine.Coverage()
+	if r.Coverage {
+		if err := writeCoverageReport(stderr(r.Stderr), coverage, r.CoverageFormat, r.CoverageFile); err != nil {
+			return nil, fmt.Errorf("write coverage: %w", err)
+		}
+	}
+
+	// The results are returned even when the coverage is below the
+	// threshold, so that they can still be written before failing.
+	return results, checkCoverageThreshold(coverage, r.CoverageThreshold)
+}